@@ -182,7 +182,7 @@ func TestRandomSubgroupElement(t *testing.T) {
 	for i, test := range tests {
 		result := RandomSubgroupElement(test.prime, test.factor)
 		if test.expected.Cmp(result) != 0 {
-			t.Errorf("expected subgroup generator not returned: %d != %d\n", i, test.expected, result)
+			t.Errorf("test %d: expected subgroup generator not returned: %d != %d\n", i, test.expected, result)
 			return
 		}
 	}
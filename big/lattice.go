@@ -0,0 +1,198 @@
+package big
+
+import (
+	"fmt"
+	"math/big"
+)
+
+//LLL reduces the rows of basis in place with the Lenstra-Lenstra-Lovász
+//algorithm using delta = 99/100. This is the integral version of the
+//algorithm from "A Course in Computational Algebraic Number Theory"
+//(Algorithm 2.6.7) so every intermediate value is an exact integer. The rows
+//of basis must be linearly independent.
+func LLL(basis [][]*big.Int) error {
+
+	n := len(basis)
+	if n == 0 {
+		return nil
+	}
+
+	dot := func(a, b []*big.Int) *big.Int {
+		sum := new(big.Int)
+		t := new(big.Int)
+		for i := range a {
+			sum = sum.Add(sum, t.Mul(a[i], b[i]))
+		}
+		return sum
+	}
+
+	//d[i+1] is the Gram determinant of the first i+1 rows and d[0] = 1.
+	//lambda[k][j] = d[j+1] * mu[k][j] for j < k.
+	d := make([]*big.Int, n+1)
+	lambda := make([][]*big.Int, n)
+	for i := range lambda {
+		lambda[i] = make([]*big.Int, n)
+		for j := range lambda[i] {
+			lambda[i][j] = new(big.Int)
+		}
+	}
+	d[0] = big.NewInt(1)
+	d[1] = dot(basis[0], basis[0])
+	if d[1].Sign() == 0 {
+		return fmt.Errorf("basis is linearly dependent")
+	}
+
+	//red size-reduces row k against row l.
+	red := func(k, l int) {
+		twice := new(big.Int).Lsh(new(big.Int).Abs(lambda[k][l]), 1)
+		if twice.Cmp(d[l+1]) <= 0 {
+			return
+		}
+		//q = round(lambda[k][l] / d[l+1])
+		q := new(big.Int).Lsh(lambda[k][l], 1)
+		q = q.Add(q, d[l+1])
+		q = q.Div(q, new(big.Int).Lsh(d[l+1], 1))
+		t := new(big.Int)
+		for i := range basis[k] {
+			basis[k][i] = basis[k][i].Sub(basis[k][i], t.Mul(q, basis[l][i]))
+		}
+		lambda[k][l] = lambda[k][l].Sub(lambda[k][l], t.Mul(q, d[l+1]))
+		for i := 0; i < l; i++ {
+			lambda[k][i] = lambda[k][i].Sub(lambda[k][i], t.Mul(q, lambda[l][i]))
+		}
+	}
+
+	kmax := 0
+	swap := func(k int) {
+		basis[k], basis[k-1] = basis[k-1], basis[k]
+		for j := 0; j < k-1; j++ {
+			lambda[k][j], lambda[k-1][j] = lambda[k-1][j], lambda[k][j]
+		}
+		lam := lambda[k][k-1]
+		//B = (d[k-1] * d[k+1] + lam^2) / d[k]
+		B := new(big.Int).Mul(d[k-1], d[k+1])
+		B = B.Add(B, new(big.Int).Mul(lam, lam))
+		B = B.Quo(B, d[k])
+		for i := k + 1; i <= kmax; i++ {
+			t := new(big.Int).Set(lambda[i][k])
+			//lambda[i][k] = (d[k+1] * lambda[i][k-1] - lam * t) / d[k]
+			nk := new(big.Int).Mul(d[k+1], lambda[i][k-1])
+			nk = nk.Sub(nk, new(big.Int).Mul(lam, t))
+			nk = nk.Quo(nk, d[k])
+			lambda[i][k] = nk
+			//lambda[i][k-1] = (B * t + lam * lambda[i][k]) / d[k+1]
+			nk1 := new(big.Int).Mul(B, t)
+			nk1 = nk1.Add(nk1, new(big.Int).Mul(lam, nk))
+			nk1 = nk1.Quo(nk1, d[k+1])
+			lambda[i][k-1] = nk1
+		}
+		d[k] = B
+	}
+
+	k := 1
+	for k < n {
+		if k > kmax {
+			//incremental Gram-Schmidt
+			kmax = k
+			for j := 0; j <= k; j++ {
+				u := dot(basis[k], basis[j])
+				for i := 0; i < j; i++ {
+					u = u.Mul(d[i+1], u)
+					u = u.Sub(u, new(big.Int).Mul(lambda[k][i], lambda[j][i]))
+					u = u.Quo(u, d[i])
+				}
+				if j < k {
+					lambda[k][j] = u
+				} else {
+					d[k+1] = u
+					if u.Sign() == 0 {
+						return fmt.Errorf("basis is linearly dependent")
+					}
+				}
+			}
+		}
+
+		red(k, k-1)
+		//Lovász condition: 100*d[k+1]*d[k-1] >= 99*d[k]^2 - 100*lambda[k][k-1]^2
+		lhs := new(big.Int).Mul(d[k+1], d[k-1])
+		lhs = lhs.Mul(lhs, big.NewInt(100))
+		rhs := new(big.Int).Mul(d[k], d[k])
+		rhs = rhs.Mul(rhs, big.NewInt(99))
+		lam2 := new(big.Int).Mul(lambda[k][k-1], lambda[k][k-1])
+		rhs = rhs.Sub(rhs, lam2.Mul(lam2, big.NewInt(100)))
+		if lhs.Cmp(rhs) < 0 {
+			swap(k)
+			if k > 1 {
+				k--
+			}
+			continue
+		}
+		for l := k - 2; l >= 0; l-- {
+			red(k, l)
+		}
+		k++
+	}
+	return nil
+}
+
+//HiddenNumberProblem looks for the hidden number x in the relations
+//  k_i = t_i * x + u_i (mod modulus)
+//where every k_i is known to be in [0, bound). It builds the lattice from
+//Boneh and Venkatesan with the k_i centred around bound/2, reduces it with
+//LLL and returns every candidate for x it finds. Callers are expected to check
+//the candidates.
+func HiddenNumberProblem(ts, us []*big.Int, modulus, bound *big.Int) (candidates []*big.Int, err error) {
+
+	m := len(ts)
+	if m == 0 || len(us) != m {
+		return nil, fmt.Errorf("need the same, non-zero number of t and u values")
+	}
+
+	//w = bound/2 so that k_i - w is in [-w, w).
+	w := new(big.Int).Rsh(bound, 1)
+	if w.Sign() == 0 {
+		w = big.NewInt(1)
+	}
+	q2 := new(big.Int).Mul(modulus, modulus)
+
+	//Rows, all scaled by the modulus to stay integral:
+	//  q^2 * e_i                        for each i
+	//  (q*t_1, ..., q*t_m, w, 0)
+	//  (q*(u_1-w), ..., q*(u_m-w), 0, q*w)
+	//The target vector is (q*(k_1-w), ..., q*(k_m-w), x*w, q*w).
+	basis := make([][]*big.Int, m+2)
+	for i := range basis {
+		basis[i] = make([]*big.Int, m+2)
+		for j := range basis[i] {
+			basis[i][j] = new(big.Int)
+		}
+	}
+	for i := 0; i < m; i++ {
+		basis[i][i].Set(q2)
+		t := new(big.Int).Mod(ts[i], modulus)
+		basis[m][i].Mul(t, modulus)
+		u := new(big.Int).Sub(us[i], w)
+		u = u.Mod(u, modulus)
+		basis[m+1][i].Mul(u, modulus)
+	}
+	basis[m][m].Set(w)
+	basis[m+1][m+1].Mul(modulus, w)
+
+	if err = LLL(basis); err != nil {
+		return nil, err
+	}
+
+	qw := new(big.Int).Mul(modulus, w)
+	for _, row := range basis {
+		last := row[m+1]
+		if new(big.Int).Abs(last).Cmp(qw) != 0 {
+			continue
+		}
+		x := new(big.Int).Quo(row[m], w)
+		if last.Sign() < 0 {
+			x = x.Neg(x)
+		}
+		candidates = append(candidates, x.Mod(x, modulus))
+	}
+	return candidates, nil
+}
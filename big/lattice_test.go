@@ -0,0 +1,81 @@
+package big
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestLLL(t *testing.T) {
+
+	//The worked example from the Wikipedia article on LLL. The reduced basis
+	//starts with a vector of length 1.
+	rows := [][]int64{
+		{1, 1, 1},
+		{-1, 0, 2},
+		{3, 5, 6},
+	}
+	basis := make([][]*big.Int, len(rows))
+	for i, row := range rows {
+		for _, v := range row {
+			basis[i] = append(basis[i], big.NewInt(v))
+		}
+	}
+	if err := LLL(basis); err != nil {
+		t.Errorf("failed to reduce basis: %v", err)
+		return
+	}
+	expected := [][]int64{
+		{0, 1, 0},
+		{1, 0, 1},
+		{-1, 0, 2},
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if basis[i][j].Int64() != expected[i][j] {
+				t.Errorf("unexpected reduced basis %v", basis)
+				return
+			}
+		}
+	}
+
+	dependent := [][]*big.Int{
+		{big.NewInt(1), big.NewInt(2)},
+		{big.NewInt(2), big.NewInt(4)},
+	}
+	if err := LLL(dependent); err == nil {
+		t.Errorf("reduced a linearly dependent basis")
+		return
+	}
+}
+
+func TestHiddenNumberProblem(t *testing.T) {
+
+	//q is the 160-bit DSA subgroup order from Cryptopals.
+	q, _ := new(big.Int).SetString("f4f47f05794b256174bba6e9b396a7707e563c5b", 16)
+	x, _ := rand.Int(rand.Reader, q)
+	bound := new(big.Int).Lsh(big.NewInt(1), 160-16)
+
+	var ts, us []*big.Int
+	for i := 0; i < 14; i++ {
+		k, _ := rand.Int(rand.Reader, bound)
+		t, _ := rand.Int(rand.Reader, q)
+		//u = k - t*x (mod q)
+		u := new(big.Int).Mul(t, x)
+		u = u.Sub(k, u)
+		ts = append(ts, t)
+		us = append(us, u.Mod(u, q))
+	}
+
+	candidates, err := HiddenNumberProblem(ts, us, q, bound)
+	if err != nil {
+		t.Errorf("failed to solve hidden number problem: %v", err)
+		return
+	}
+	for _, candidate := range candidates {
+		if candidate.Cmp(x) == 0 {
+			return
+		}
+	}
+	t.Errorf("hidden number was not among %d candidates", len(candidates))
+}
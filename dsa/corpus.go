@@ -0,0 +1,183 @@
+package dsa
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+//SignatureRecord is a single signature (r,s) over Message taken from a
+//signature log. Digest is the message digest as an integer. If a log does not
+//supply it, it is the SHA-1 digest of Message.
+type SignatureRecord struct {
+	Message []byte
+	R, S    *big.Int
+	Digest  *big.Int
+}
+
+//NonceReuse reports a pair of records in a corpus that were signed with the
+//same nonce K. The indices refer to the slice passed to FindReusedNonces.
+type NonceReuse struct {
+	I, J int
+	K    *big.Int
+}
+
+//ReadSignatureRecords parses signature records in the format of Cryptopals
+//challenge 44. Each record is four lines:
+//  msg: <message>
+//  s: <decimal s>
+//  r: <decimal r>
+//  m: <hex SHA-1 digest of message>
+func ReadSignatureRecords(reader io.Reader) (records []SignatureRecord, err error) {
+
+	scanner := bufio.NewScanner(reader)
+	var record SignatureRecord
+	line := 0
+	for scanner.Scan() {
+		text := scanner.Text()
+		line += 1
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		field := strings.SplitN(text, ": ", 2)
+		if len(field) != 2 {
+			return nil, fmt.Errorf("line %d: malformed record", line)
+		}
+		var ok bool
+		switch field[0] {
+		case "msg":
+			record = SignatureRecord{Message: []byte(field[1])}
+			ok = true
+		case "s":
+			record.S, ok = new(big.Int).SetString(field[1], 10)
+		case "r":
+			record.R, ok = new(big.Int).SetString(field[1], 10)
+		case "m":
+			record.Digest, ok = new(big.Int).SetString(field[1], 16)
+			if ok && record.R != nil && record.S != nil {
+				records = append(records, record)
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: malformed %q field", line, field[0])
+		}
+	}
+	return records, scanner.Err()
+}
+
+//jsonSignatureRecord is the JSON form of a SignatureRecord. R and S are
+//decimal strings and M is an optional hex digest.
+type jsonSignatureRecord struct {
+	Msg string `json:"msg"`
+	R   string `json:"r"`
+	S   string `json:"s"`
+	M   string `json:"m,omitempty"`
+}
+
+//ReadSignatureRecordsJSON parses signature records from JSON. The input can
+//be a single array of records or a stream of records, one after another:
+//  {"msg": "...", "r": "<decimal>", "s": "<decimal>", "m": "<hex>"}
+//The "m" field is optional and defaults to the SHA-1 digest of "msg".
+func ReadSignatureRecordsJSON(reader io.Reader) (records []SignatureRecord, err error) {
+
+	decoder := json.NewDecoder(reader)
+	var raw []jsonSignatureRecord
+	for {
+		var next json.RawMessage
+		if err = decoder.Decode(&next); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		trimmed := bytes.TrimSpace(next)
+		if len(trimmed) > 0 && trimmed[0] == '[' {
+			var many []jsonSignatureRecord
+			if err = json.Unmarshal(trimmed, &many); err != nil {
+				return nil, err
+			}
+			raw = append(raw, many...)
+			continue
+		}
+		var single jsonSignatureRecord
+		if err = json.Unmarshal(trimmed, &single); err != nil {
+			return nil, err
+		}
+		raw = append(raw, single)
+	}
+
+	for i, rec := range raw {
+		record := SignatureRecord{Message: []byte(rec.Msg)}
+		var ok1, ok2, ok3 bool
+		record.R, ok1 = new(big.Int).SetString(rec.R, 10)
+		record.S, ok2 = new(big.Int).SetString(rec.S, 10)
+		if rec.M == "" {
+			digest := sha1.Sum(record.Message)
+			record.Digest, ok3 = new(big.Int).SetBytes(digest[:]), true
+		} else {
+			record.Digest, ok3 = new(big.Int).SetString(rec.M, 16)
+		}
+		if !ok1 || !ok2 || !ok3 {
+			return nil, fmt.Errorf("record %d: malformed field", i)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+//FindReusedNonces groups records by r and looks for pairs that were signed
+//with the same nonce. For a pair with different digests the nonce is
+//  k = (m1 - m2) / (s1 - s2) (mod q)
+//and the private key follows from RecoverPrivateKeyFromSubKey. A candidate
+//key is only accepted if g^x = y. All pairs that leaked the key are returned
+//in leaks. An error is returned if no pair leaked the key.
+func FindReusedNonces(records []SignatureRecord, publicKey *PublicKey) (x *big.Int, leaks []NonceReuse, err error) {
+
+	Q := publicKey.Q
+	groups := make(map[string][]int)
+	var order []string
+	for i, record := range records {
+		key := record.R.String()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		for a := 0; a < len(group); a++ {
+			for b := a + 1; b < len(group); b++ {
+				r1, r2 := records[group[a]], records[group[b]]
+				ds := new(big.Int).Sub(r1.S, r2.S)
+				ds = ds.Mod(ds, Q)
+				dm := new(big.Int).Sub(r1.Digest, r2.Digest)
+				dm = dm.Mod(dm, Q)
+				//the same message signed twice with the same nonce
+				//gives the same s and leaks nothing.
+				if ds.Sign() == 0 || dm.Sign() == 0 {
+					continue
+				}
+				k := ds.ModInverse(ds, Q)
+				k = k.Mul(k, dm)
+				k = k.Mod(k, Q)
+
+				candidate := recoverPrivateKeyFromDigest(r1.Digest, r1.R, r1.S, k, publicKey)
+				if new(big.Int).Exp(publicKey.G, candidate, publicKey.P).Cmp(publicKey.Y) != 0 {
+					continue
+				}
+				x = candidate
+				leaks = append(leaks, NonceReuse{I: group[a], J: group[b], K: k})
+			}
+		}
+	}
+
+	if x == nil {
+		return nil, nil, fmt.Errorf("no reused nonces leaked the private key")
+	}
+	return x, leaks, nil
+}
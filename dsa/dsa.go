@@ -1,9 +1,11 @@
 package dsa
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/sha1"
+	"errors"
 	"fmt"
+	badbig "github.com/kelbyludwig/badcrypto/big"
 	"math/big"
 )
 
@@ -19,6 +21,9 @@ type PrivateKey struct {
 	X         *big.Int
 }
 
+var zero *big.Int = big.NewInt(0)
+var one *big.Int = big.NewInt(1)
+
 //p,q, and g are hardcoded DSA parameters that
 //are set by init().
 var p *big.Int
@@ -50,36 +55,82 @@ func init() {
 //GenerateKey generates a new DSA signing keypair.
 //GenerateKey uses a fixed set of parameters for simplicity.
 func GenerateKey() (priv *PrivateKey, err error) {
+	return GenerateKeyWithParameters(defaultParameters())
+}
+
+//GenerateKeyWithParameters generates a new DSA signing keypair that uses the
+//supplied domain parameters.
+func GenerateKeyWithParameters(params *Parameters) (priv *PrivateKey, err error) {
 	priv = new(PrivateKey)
 	priv.PublicKey = new(PublicKey)
-	priv.PublicKey.P = p
-	priv.PublicKey.G = g
-	priv.PublicKey.Q = q
+	priv.PublicKey.P = params.P
+	priv.PublicKey.G = params.G
+	priv.PublicKey.Q = params.Q
 
-	x := make([]byte, len(q.Bytes()))
-	_, err = rand.Read(x)
-	if err != nil {
-		return
+	x := make([]byte, len(params.Q.Bytes()))
+	for {
+		_, err = rand.Read(x)
+		if err != nil {
+			return
+		}
+		priv.X = new(big.Int).SetBytes(x)
+		priv.X = priv.X.Mod(priv.X, params.Q)
+		if priv.X.Sign() != 0 {
+			break
+		}
 	}
-	priv.X = new(big.Int).SetBytes(x)
-	priv.X = priv.X.Mod(priv.X, q)
-	priv.PublicKey.Y = new(big.Int).Exp(g, priv.X, p)
+	priv.PublicKey.Y = new(big.Int).Exp(params.G, priv.X, params.P)
 	return
 }
 
-//Sign DSA signs message using the supplied private key.
-func Sign(message []byte, privateKey *PrivateKey) (r, s *big.Int, err error) {
+//ErrRangeCheck is returned by Verify when r or s is not in [1, q).
+var ErrRangeCheck = errors.New("dsa: signature value out of range")
+
+//ErrMismatch is returned by Verify when a well-formed signature does not
+//match the message and public key.
+var ErrMismatch = errors.New("dsa: signature does not match")
+
+//ErrInvalidGenerator is returned by Verify and SchnorrVerify when the
+//generator of the public key is not in (1, p) or does not have order q.
+var ErrInvalidGenerator = errors.New("dsa: invalid generator")
+
+//hashMessage hashes message with hash and converts the leftmost N bits of the
+//digest to an integer, where N is the bit length of q (FIPS 186-4 Section 4.6).
+//The full digest is returned as well.
+func hashMessage(message []byte, hash crypto.Hash, Q *big.Int) (z *big.Int, digest []byte, err error) {
+	if !hash.Available() {
+		return nil, nil, fmt.Errorf("hash function is not available")
+	}
+	h := hash.New()
+	h.Write(message)
+	digest = h.Sum(nil)
+	z = new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - Q.BitLen(); excess > 0 {
+		z = z.Rsh(z, uint(excess))
+	}
+	return z, digest, nil
+}
+
+//Sign DSA signs message using the supplied private key and hash function.
+func Sign(message []byte, hash crypto.Hash, privateKey *PrivateKey) (r, s *big.Int, err error) {
+	return SignWithNonce(message, hash, privateKey, RandomNonce)
+}
 
+//SignWithNonce DSA signs message using the supplied private key and hash
+//function and draws the per-message key k from nonce.
+func SignWithNonce(message []byte, hash crypto.Hash, privateKey *PrivateKey, nonce NonceGenerator) (r, s *big.Int, err error) {
+
+	digestNum, digest, err := hashMessage(message, hash, privateKey.PublicKey.Q)
+	if err != nil {
+		return
+	}
 	var k *big.Int
-	kbuf := make([]byte, len(privateKey.PublicKey.Q.Bytes()))
 	for {
 		//Generate per-message key
-		_, err = rand.Read(kbuf)
+		k, err = nonce(digest, privateKey)
 		if err != nil {
 			return
 		}
-		k = new(big.Int).SetBytes(kbuf)
-		k = k.Mod(k, privateKey.PublicKey.Q)
 
 		r = new(big.Int).Exp(privateKey.PublicKey.G, k, privateKey.PublicKey.P)
 		r = r.Mod(r, privateKey.PublicKey.Q)
@@ -90,8 +141,6 @@ func Sign(message []byte, privateKey *PrivateKey) (r, s *big.Int, err error) {
 	}
 	kinv := new(big.Int).ModInverse(k, privateKey.PublicKey.Q)
 	xr := new(big.Int).Mul(privateKey.X, r)
-	digest := sha1.Sum(message)
-	digestNum := new(big.Int).SetBytes(digest[:])
 	s = new(big.Int).Add(digestNum, xr)
 	s = s.Mod(s, privateKey.PublicKey.Q)
 	s = s.Mul(s, kinv)
@@ -99,23 +148,60 @@ func Sign(message []byte, privateKey *PrivateKey) (r, s *big.Int, err error) {
 	return
 }
 
-//Verify verifies a signature (r,s) for message under the supplied publicKey.
-//Returns a non-nil error on signature validation failure.
-func Verify(message []byte, r, s *big.Int, publicKey *PublicKey) error {
+//Verify verifies a signature (r,s) for message under the supplied publicKey
+//and hash function. It returns ErrRangeCheck if r or s is not in [1, q) and
+//ErrMismatch if the signature does not match. Verify rejects public keys with
+//a degenerate generator such as g = 0 or g = p+1 with ErrInvalidGenerator.
+func Verify(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) error {
+
+	if err := checkGenerator(publicKey); err != nil {
+		return err
+	}
 
 	//Reject the signature if 0<r<q or 0<s<q is not satisfied.
-	zero := big.NewInt(0)
-	if r.Cmp(zero) <= 0 ||
-		s.Cmp(zero) <= 0 ||
-		r.Cmp(publicKey.Q) == 1 ||
-		s.Cmp(publicKey.Q) == 1 {
-		fmt.Printf("1\n")
-		return fmt.Errorf("invalid signature")
+	if r.Sign() <= 0 ||
+		s.Sign() <= 0 ||
+		r.Cmp(publicKey.Q) >= 0 ||
+		s.Cmp(publicKey.Q) >= 0 {
+		return ErrRangeCheck
+	}
+
+	return verifyEquation(message, r, s, hash, publicKey)
+}
+
+//VerifyUnsafe verifies a signature (r,s) like Verify but trusts the
+//generator in publicKey and skips the 0<r check. With g = 0 the signature
+//(0, s) is valid for any message.
+func VerifyUnsafe(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) error {
+
+	//s still needs to be invertible mod q.
+	if s.Sign() <= 0 || s.Cmp(publicKey.Q) >= 0 || r.Cmp(publicKey.Q) >= 0 {
+		return ErrRangeCheck
 	}
 
+	return verifyEquation(message, r, s, hash, publicKey)
+}
+
+//checkGenerator returns ErrInvalidGenerator if the generator of publicKey
+//does not satisfy 1 < g < p and g^q = 1 (mod p).
+func checkGenerator(publicKey *PublicKey) error {
+	if publicKey.G.Cmp(one) <= 0 || publicKey.G.Cmp(publicKey.P) >= 0 {
+		return ErrInvalidGenerator
+	}
+	if new(big.Int).Exp(publicKey.G, publicKey.Q, publicKey.P).Cmp(one) != 0 {
+		return ErrInvalidGenerator
+	}
+	return nil
+}
+
+//verifyEquation checks that (g^u1 * y^u2 mod p) mod q = r.
+func verifyEquation(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) error {
+
+	digestNum, _, err := hashMessage(message, hash, publicKey.Q)
+	if err != nil {
+		return err
+	}
 	w := new(big.Int).ModInverse(s, publicKey.Q)
-	digest := sha1.Sum(message)
-	digestNum := new(big.Int).SetBytes(digest[:])
 	u1 := new(big.Int).Mul(digestNum, w)
 	u1 = u1.Mod(u1, publicKey.Q)
 	u2 := new(big.Int).Mul(r, w)
@@ -127,19 +213,26 @@ func Verify(message []byte, r, s *big.Int, publicKey *PublicKey) error {
 	v := new(big.Int).Mod(gu, publicKey.Q)
 
 	if v.Cmp(r) != 0 {
-		fmt.Printf("2\n")
-		fmt.Printf("v: %x\n", v)
-		fmt.Printf("r: %x\n", r)
-		return fmt.Errorf("invalid signature")
-	} else {
-		return nil
+		return ErrMismatch
 	}
+	return nil
+}
 
+//RecoverPrivateKeyFromSubKey recovers the private key x from a signature
+//(r,s) over message when the nonce k used to make it is known:
+//  x = (s*k - H(m)) / r (mod q)
+//It returns nil if hash is not available.
+func RecoverPrivateKeyFromSubKey(message []byte, r, s, k *big.Int, hash crypto.Hash, publicKey *PublicKey) (x *big.Int) {
+	msgBig, _, err := hashMessage(message, hash, publicKey.Q)
+	if err != nil {
+		return nil
+	}
+	return recoverPrivateKeyFromDigest(msgBig, r, s, k, publicKey)
 }
 
-func RecoverPrivateKeyFromSubKey(message []byte, r, s, k *big.Int, publicKey *PublicKey) (x *big.Int) {
-	msgDigest := sha1.Sum(message)
-	msgBig := new(big.Int).SetBytes(msgDigest[:])
+//recoverPrivateKeyFromDigest is RecoverPrivateKeyFromSubKey for a message
+//digest that has already been converted to an integer.
+func recoverPrivateKeyFromDigest(msgBig, r, s, k *big.Int, publicKey *PublicKey) (x *big.Int) {
 	rinv := new(big.Int).ModInverse(r, publicKey.Q)
 	x = new(big.Int).Mul(s, k)
 	x = x.Sub(x, msgBig)
@@ -147,3 +240,109 @@ func RecoverPrivateKeyFromSubKey(message []byte, r, s, k *big.Int, publicKey *Pu
 	x = x.Mod(x, publicKey.Q)
 	return
 }
+
+//RecoverKeyFromDigestNonce recovers the private key behind a signature made
+//with DigestNonce. The nonce is H(m) mod q, which anyone holding the message
+//can compute, so the key follows directly from RecoverPrivateKeyFromSubKey.
+func RecoverKeyFromDigestNonce(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) (x *big.Int, err error) {
+	msgBig, digest, err := hashMessage(message, hash, publicKey.Q)
+	if err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(digest)
+	k = k.Mod(k, publicKey.Q)
+	x = recoverPrivateKeyFromDigest(msgBig, r, s, k, publicKey)
+	if new(big.Int).Exp(publicKey.G, x, publicKey.P).Cmp(publicKey.Y) != 0 {
+		return nil, fmt.Errorf("signature was not made with a digest nonce")
+	}
+	return x, nil
+}
+
+//kangarooAttempts is the number of walks RecoverKeyFromBoundedNonce tries
+//before giving up.
+const kangarooAttempts = 4
+
+//bruteForceLimit is the largest nonce range RecoverKeyFromBoundedNonce will
+//search exhaustively before switching to Pollard's kangaroo.
+var bruteForceLimit = big.NewInt(1 << 20)
+
+//RecoverKeyFromBoundedNonce recovers the private key behind the signature
+//(r,s) over message when the nonce k used to make it is known to be in
+//[kMin, kMax]. Small ranges are searched exhaustively by stepping g^k. Larger
+//ranges use Pollard's kangaroo on g^k mod p, which can be rebuilt from the
+//signature as g^(H(m)/s) * y^(r/s). The kangaroo is probabilistic, so it is
+//retried a few times with the range shifted by a random amount. Every
+//candidate x is checked against g^x = y.
+func RecoverKeyFromBoundedNonce(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey, kMin, kMax *big.Int) (x *big.Int, err error) {
+
+	P, Q, G := publicKey.P, publicKey.Q, publicKey.G
+	msgBig, _, err := hashMessage(message, hash, Q)
+	if err != nil {
+		return nil, err
+	}
+	check := func(k *big.Int) *big.Int {
+		x := recoverPrivateKeyFromDigest(msgBig, r, s, k, publicKey)
+		if new(big.Int).Exp(G, x, P).Cmp(publicKey.Y) == 0 {
+			return x
+		}
+		return nil
+	}
+
+	width := new(big.Int).Sub(kMax, kMin)
+	if width.Sign() < 0 {
+		return nil, fmt.Errorf("empty nonce range")
+	}
+
+	if width.Cmp(bruteForceLimit) <= 0 {
+		gk := new(big.Int).Exp(G, kMin, P)
+		rk := new(big.Int)
+		for k := new(big.Int).Set(kMin); k.Cmp(kMax) <= 0; k = k.Add(k, one) {
+			if rk.Mod(gk, Q).Cmp(r) == 0 {
+				if x = check(k); x != nil {
+					return x, nil
+				}
+			}
+			gk = gk.Mul(gk, G)
+			gk = gk.Mod(gk, P)
+		}
+		return nil, fmt.Errorf("nonce not found in range")
+	}
+
+	//g^k = g^(H(m)/s) * y^(r/s) (mod p)
+	w := new(big.Int).ModInverse(s, Q)
+	if w == nil {
+		return nil, fmt.Errorf("s is not invertible")
+	}
+	u1 := new(big.Int).Mul(msgBig, w)
+	u1 = u1.Mod(u1, Q)
+	u2 := new(big.Int).Mul(r, w)
+	u2 = u2.Mod(u2, Q)
+	gk := new(big.Int).Exp(G, u1, P)
+	gk = gk.Mul(gk, new(big.Int).Exp(publicKey.Y, u2, P))
+	gk = gk.Mod(gk, P)
+	if new(big.Int).Mod(gk, Q).Cmp(r) != 0 {
+		return nil, fmt.Errorf("signature is not valid")
+	}
+
+	//the kangaroo can miss, so later attempts shift the range by a random
+	//amount for a different walk
+	for i := 0; i < kangarooAttempts; i++ {
+		shift := big.NewInt(0)
+		if i > 0 {
+			shift, _ = rand.Int(rand.Reader, width)
+		}
+		target := new(big.Int).Exp(G, shift, P)
+		target = target.Mul(target, gk)
+		target = target.Mod(target, P)
+		lo := new(big.Int).Add(kMin, shift)
+		hi := new(big.Int).Add(kMax, shift)
+		k, err := badbig.Kangaroo(target, G, P, lo, hi)
+		if err != nil {
+			continue
+		}
+		if x = check(k.Sub(k, shift)); x != nil {
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("nonce not found in range")
+}
@@ -1,7 +1,8 @@
 package dsa
 
 import (
-	"bufio"
+	"bytes"
+	"crypto"
 	"crypto/sha1"
 	"fmt"
 	"math/big"
@@ -18,14 +19,14 @@ func TestDSASign(t *testing.T) {
 		return
 	}
 
-	r, s, err := Sign(message, priv)
+	r, s, err := Sign(message, crypto.SHA1, priv)
 
 	if err != nil {
 		t.Errorf("failed to sign message")
 		return
 	}
 
-	err = Verify(message, r, s, priv.PublicKey)
+	err = Verify(message, r, s, crypto.SHA1, priv.PublicKey)
 
 	if err != nil {
 		t.Errorf("failed to verify signature")
@@ -34,6 +35,55 @@ func TestDSASign(t *testing.T) {
 
 }
 
+func TestVerifyErrors(t *testing.T) {
+
+	message := []byte("i'm walking here!")
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	r, s, err := Sign(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if err = Verify(message, r, s, crypto.SHA256, priv.PublicKey); err != nil {
+		t.Errorf("failed to verify signature")
+		return
+	}
+
+	Q := priv.PublicKey.Q
+	tests := []struct {
+		r, s *big.Int
+		hash crypto.Hash
+		err  error
+	}{
+		{r, s, crypto.SHA1, ErrMismatch},
+		{new(big.Int).Add(r, one), s, crypto.SHA256, ErrMismatch},
+		{zero, s, crypto.SHA256, ErrRangeCheck},
+		{r, zero, crypto.SHA256, ErrRangeCheck},
+		{Q, s, crypto.SHA256, ErrRangeCheck},
+		{r, Q, crypto.SHA256, ErrRangeCheck},
+		//r + q is congruent to r but out of range
+		{new(big.Int).Add(r, Q), s, crypto.SHA256, ErrRangeCheck},
+	}
+	for i, test := range tests {
+		if err = Verify(message, test.r, test.s, test.hash, priv.PublicKey); err != test.err {
+			t.Errorf("test %d: expected %v but got %v", i, test.err, err)
+			return
+		}
+	}
+
+	//g = p - 1 is in range but has order 2
+	pub := *priv.PublicKey
+	pub.G = new(big.Int).Sub(pub.P, one)
+	if err = Verify(message, r, s, crypto.SHA256, &pub); err != ErrInvalidGenerator {
+		t.Errorf("expected %v but got %v", ErrInvalidGenerator, err)
+		return
+	}
+}
+
 //TestRecoverPrivateKeyFromSubKey is a test for Cryptopals Set 6 Challenge 43
 func TestRecoverPrivateKeyFromSubKey(t *testing.T) {
 	msg := []byte("For those that envy a MC it can be hazardous to your health\n" +
@@ -71,7 +121,7 @@ func TestRecoverPrivateKeyFromSubKey(t *testing.T) {
 
 	for i := 0; i <= 65536; i++ {
 		k := big.NewInt(int64(i))
-		x := RecoverPrivateKeyFromSubKey(msg, r, s, k, priv.PublicKey)
+		x := RecoverPrivateKeyFromSubKey(msg, r, s, k, crypto.SHA1, priv.PublicKey)
 		xHex := fmt.Sprintf("%x", x)
 		xDigest := sha1.Sum([]byte(xHex))
 		xHex = fmt.Sprintf("%x", xDigest)
@@ -84,78 +134,162 @@ func TestRecoverPrivateKeyFromSubKey(t *testing.T) {
 
 }
 
-//TestDSANonceReuse is a test for Cryptopals Set 6 Challenge 44
-func TestDSANonceReuse(t *testing.T) {
+func TestRecoverKeyFromBoundedNonce(t *testing.T) {
 
 	priv, err := GenerateKey()
-
 	if err != nil {
 		t.Errorf("error generating keypair")
 		return
 	}
+	message := []byte("i'm walking here!")
+
+	ranges := [][2]*big.Int{
+		{big.NewInt(1), big.NewInt(1 << 16)},
+		//too wide to search exhaustively, so this uses the kangaroo
+		{big.NewInt(1 << 30), big.NewInt(1<<30 + 1<<24)},
+	}
+	for _, kRange := range ranges {
+		r, s, err := SignWithNonce(message, crypto.SHA1, priv, BoundedNonce(kRange[0], kRange[1]))
+		if err != nil {
+			t.Errorf("failed to sign message")
+			return
+		}
+		x, err := RecoverKeyFromBoundedNonce(message, r, s, crypto.SHA1, priv.PublicKey, kRange[0], kRange[1])
+		if err != nil {
+			t.Errorf("failed to recover private key: %v", err)
+			return
+		}
+		if x.Cmp(priv.X) != 0 {
+			t.Errorf("recovered the wrong private key")
+			return
+		}
+	}
+
+	//the key from Cryptopals Set 6 Challenge 43
+	msg := []byte("For those that envy a MC it can be hazardous to your health\n" +
+		"So be friendly, a matter of life and death, just like a etch-a-sketch\n")
+	y, ok0 := new(big.Int).SetString("84ad4719d044495496a3201c8ff484feb45b962e7302e56a392aee4"+
+		"abab3e4bdebf2955b4736012f21a08084056b19bcd7fee56048e004"+
+		"e44984e2f411788efdc837a0d2e5abb7b555039fd243ac01f0fb2ed"+
+		"1dec568280ce678e931868d23eb095fde9d3779191b8c0299d6e07b"+
+		"bb283e6633451e535c45513b2d33c99ea17", 16)
+	r, ok1 := new(big.Int).SetString("548099063082341131477253921760299949438196259240", 10)
+	s, ok2 := new(big.Int).SetString("857042759984254168557880549501802188789837994940", 10)
+
+	if !ok0 || !ok1 || !ok2 {
+		t.Errorf("failed to generate signature")
+		return
+	}
+	pub := &PublicKey{P: p, Q: q, G: g, Y: y}
+
+	x, err := RecoverKeyFromBoundedNonce(msg, r, s, crypto.SHA1, pub, big.NewInt(0), big.NewInt(65536))
+	if err != nil {
+		t.Errorf("did not find matching private key")
+		return
+	}
+	xHex := fmt.Sprintf("%x", x)
+	xDigest := sha1.Sum([]byte(xHex))
+	xHex = fmt.Sprintf("%x", xDigest)
+	if xHex != "0954edd5e0afe5542a4adf012611a91912a3ec16" {
+		t.Errorf("recovered the wrong private key")
+		return
+	}
+}
+
+//TestDSANonceReuse is a test for Cryptopals Set 6 Challenge 44
+func TestDSANonceReuse(t *testing.T) {
 
 	file, err := os.Open("44.txt")
-	defer file.Close()
 	if err != nil {
 		t.Errorf("failed to open 44.txt")
 		return
 	}
+	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	i := 0
-	var msgs [][]byte
-	var ss, rs, ms []*big.Int
-	hex2bn := func(s string) *big.Int {
-		b, _ := new(big.Int).SetString(s, 16)
-		return b
-	}
-	dec2bn := func(s string) *big.Int {
-		b, _ := new(big.Int).SetString(s, 10)
-		return b
-	}
-	for scanner.Scan() {
-		line := scanner.Text()
-		i += 1
-		switch i % 4 {
-		case 1:
-			msg := line[5:]
-			msgs = append(msgs, []byte(msg))
-		case 2:
-			s := line[3:]
-			ss = append(ss, dec2bn(s))
-		case 3:
-			r := line[3:]
-			rs = append(rs, dec2bn(r))
-		case 0:
-			m := line[3:]
-			ms = append(ms, hex2bn(m))
-		}
+	records, err := ReadSignatureRecords(file)
+	if err != nil {
+		t.Errorf("failed to read 44.txt: %v", err)
+		return
+	}
+
+	y, _ := new(big.Int).SetString("2d026f4bf30195ede3a088da85e398ef869611d0f68f0713d51c9c1a3a26c951"+
+		"05d915e2d8cdf26d056b86b8a7b85519b1c23cc3ecdc6062650462e3063bd179"+
+		"c2a6581519f674a61f1d89a1fff27171ebc1b93d4dc57bceb7ae2430f98a6a4d"+
+		"83d8279ee65d71c1203d2c96d65ebbf7cce9d32971c3de5084cce04a2e147821", 16)
+	pub := &PublicKey{P: p, Q: q, G: g, Y: y}
+
+	x, leaks, err := FindReusedNonces(records, pub)
+	if err != nil {
+		t.Errorf("failed to recover private key: %v", err)
+		return
+	}
+	xHex := fmt.Sprintf("%x", x)
+	xDigest := sha1.Sum([]byte(xHex))
+	xHex = fmt.Sprintf("%x", xDigest)
+	if xHex != "ca8f6f7c66fa362d40760d135b763eb8527d3d52" {
+		t.Errorf("recovered the wrong private key")
+		return
+	}
+	t.Logf("Recovered private key: %x\n", x)
+	for _, leak := range leaks {
+		t.Logf("Signatures %d and %d shared k = %x\n", leak.I, leak.J, leak.K)
+	}
+}
+
+func TestFindReusedNoncesJSON(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("error generating keypair")
+		return
 	}
 
-	if len(ss) != len(rs) || len(rs) != len(ms) {
-		t.Errorf("lengths of signatures read from file were wrong")
-		return
-	}
-
-	for i, x := range ms {
-		for j, y := range ms {
-			//messages were the same. lets skip.
-			if x.Cmp(y) == 0 {
-				continue
-			}
-			sinv := new(big.Int).Sub(ss[i], ss[j])
-			sinv = sinv.ModInverse(sinv, priv.PublicKey.Q)
-			k := new(big.Int).Sub(ms[i], ms[j])
-			k = k.Mul(k, sinv)
-			k = k.Mod(k, priv.PublicKey.Q)
-			x := RecoverPrivateKeyFromSubKey(msgs[i], rs[i], ss[i], k, priv.PublicKey)
-			xHex := fmt.Sprintf("%x", x)
-			xDigest := sha1.Sum([]byte(xHex))
-			xHex = fmt.Sprintf("%x", xDigest)
-			if xHex == "ca8f6f7c66fa362d40760d135b763eb8527d3d52" {
-				t.Logf("Recovered private key: %x\n", x)
-				return
-			}
+	k := big.NewInt(31337)
+	fixed := func(digest []byte, privateKey *PrivateKey) (*big.Int, error) {
+		return k, nil
+	}
+	var buf bytes.Buffer
+	messages := []string{"first", "second", "third"}
+	for i, message := range messages {
+		nonce := RandomNonce
+		if i != 1 {
+			nonce = fixed
+		}
+		r, s, err := SignWithNonce([]byte(message), crypto.SHA1, priv, nonce)
+		if err != nil {
+			t.Errorf("failed to sign message")
+			return
 		}
+		fmt.Fprintf(&buf, "{\"msg\": %q, \"r\": \"%d\", \"s\": \"%d\"}\n", message, r, s)
+	}
+
+	records, err := ReadSignatureRecordsJSON(&buf)
+	if err != nil {
+		t.Errorf("failed to read records: %v", err)
+		return
+	}
+	if len(records) != len(messages) {
+		t.Errorf("read the wrong number of records")
+		return
+	}
+
+	x, leaks, err := FindReusedNonces(records, priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to recover private key: %v", err)
+		return
+	}
+	if x.Cmp(priv.X) != 0 {
+		t.Errorf("recovered the wrong private key")
+		return
+	}
+	if len(leaks) != 1 || leaks[0].I != 0 || leaks[0].J != 2 || leaks[0].K.Cmp(k) != 0 {
+		t.Errorf("reported the wrong leaked signatures: %v", leaks)
+		return
+	}
+
+	//a corpus without reuse leaks nothing
+	if _, _, err = FindReusedNonces(records[:2], priv.PublicKey); err == nil {
+		t.Errorf("recovered a key without nonce reuse")
+		return
 	}
 }
@@ -0,0 +1,189 @@
+package dsa
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+
+	badbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+//ElGamalCiphertext is an ElGamal ciphertext (c1, c2) = (g^k, M * y^k) where M
+//is the message for multiplicative ElGamal and g^m for exponential ElGamal.
+type ElGamalCiphertext struct {
+	C1, C2 *big.Int
+}
+
+//elgamalEncrypt encrypts the group element M under publicKey.
+func elgamalEncrypt(M *big.Int, publicKey *PublicKey) (ct *ElGamalCiphertext, err error) {
+	k, err := RandomNonce(nil, &PrivateKey{PublicKey: publicKey})
+	if err != nil {
+		return
+	}
+	c1 := new(big.Int).Exp(publicKey.G, k, publicKey.P)
+	c2 := new(big.Int).Exp(publicKey.Y, k, publicKey.P)
+	c2 = c2.Mul(c2, M)
+	c2 = c2.Mod(c2, publicKey.P)
+	return &ElGamalCiphertext{C1: c1, C2: c2}, nil
+}
+
+//elgamalDecrypt returns M = c2 / c1^x.
+func elgamalDecrypt(ct *ElGamalCiphertext, privateKey *PrivateKey) (M *big.Int, err error) {
+	P := privateKey.PublicKey.P
+	if ct.C1.Sign() <= 0 || ct.C1.Cmp(P) >= 0 || ct.C2.Sign() <= 0 || ct.C2.Cmp(P) >= 0 {
+		return nil, fmt.Errorf("ciphertext is out of range")
+	}
+	shared := new(big.Int).Exp(ct.C1, privateKey.X, P)
+	M = shared.ModInverse(shared, P)
+	M = M.Mul(M, ct.C2)
+	return M.Mod(M, P), nil
+}
+
+//ElGamalEncrypt encrypts m with multiplicative ElGamal over the group of
+//publicKey. m must be in [1, p). Since m is used as is rather than being
+//mapped into the order q subgroup, the ciphertext leaks whether m is in it.
+//Ciphertexts are malleable: multiplying c2 by t decrypts to t*m.
+func ElGamalEncrypt(m *big.Int, publicKey *PublicKey) (ct *ElGamalCiphertext, err error) {
+	if m.Sign() <= 0 || m.Cmp(publicKey.P) >= 0 {
+		return nil, fmt.Errorf("message is out of range")
+	}
+	return elgamalEncrypt(m, publicKey)
+}
+
+//ElGamalDecrypt decrypts a multiplicative ElGamal ciphertext.
+func ElGamalDecrypt(ct *ElGamalCiphertext, privateKey *PrivateKey) (m *big.Int, err error) {
+	return elgamalDecrypt(ct, privateKey)
+}
+
+//ElGamalEncryptExponential encrypts m with exponential ElGamal, which hides
+//g^m instead of m. The scheme is additively homomorphic: ElGamalMul of two
+//ciphertexts decrypts to the sum of their messages. m must be in [0, q).
+func ElGamalEncryptExponential(m *big.Int, publicKey *PublicKey) (ct *ElGamalCiphertext, err error) {
+	if m.Sign() < 0 || m.Cmp(publicKey.Q) >= 0 {
+		return nil, fmt.Errorf("message is out of range")
+	}
+	return elgamalEncrypt(new(big.Int).Exp(publicKey.G, m, publicKey.P), publicKey)
+}
+
+//ElGamalDecryptExponential decrypts an exponential ElGamal ciphertext. The
+//decryption only gives g^m, so m is found with Pollard's kangaroo and has to
+//be in [0, bound].
+func ElGamalDecryptExponential(ct *ElGamalCiphertext, privateKey *PrivateKey, bound *big.Int) (m *big.Int, err error) {
+	gm, err := elgamalDecrypt(ct, privateKey)
+	if err != nil {
+		return
+	}
+	if gm.Cmp(one) == 0 {
+		return big.NewInt(0), nil
+	}
+	pub := privateKey.PublicKey
+	return badbig.Kangaroo(gm, pub.G, pub.P, big.NewInt(0), bound)
+}
+
+//ElGamalMul multiplies two ciphertexts component-wise. For multiplicative
+//ElGamal the result decrypts to the product of the messages and for
+//exponential ElGamal to their sum. Anyone holding the public key can encrypt
+//a factor of their choosing, so neither variant is non-malleable.
+func ElGamalMul(a, b *ElGamalCiphertext, publicKey *PublicKey) *ElGamalCiphertext {
+	c1 := new(big.Int).Mul(a.C1, b.C1)
+	c1 = c1.Mod(c1, publicKey.P)
+	c2 := new(big.Int).Mul(a.C2, b.C2)
+	c2 = c2.Mod(c2, publicKey.P)
+	return &ElGamalCiphertext{C1: c1, C2: c2}
+}
+
+//ElGamalSign signs message with the ElGamal signature scheme over the order q
+//subgroup:
+//  r = g^k mod p
+//  s = (H(m) - x*r) / k (mod q)
+//The digest is truncated to the bit length of q like DSA.
+func ElGamalSign(message []byte, hash crypto.Hash, privateKey *PrivateKey) (r, s *big.Int, err error) {
+	z, _, err := hashMessage(message, hash, privateKey.PublicKey.Q)
+	if err != nil {
+		return
+	}
+	return ElGamalSignUnhashed(z, privateKey)
+}
+
+//ElGamalSignUnhashed is ElGamalSign for a message that is already an integer
+//and is signed without hashing it.
+func ElGamalSignUnhashed(m *big.Int, privateKey *PrivateKey) (r, s *big.Int, err error) {
+	pub := privateKey.PublicKey
+	Q := pub.Q
+	for {
+		k, err := RandomNonce(nil, privateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		r = new(big.Int).Exp(pub.G, k, pub.P)
+		s = new(big.Int).Mul(privateKey.X, r)
+		s = s.Sub(m, s)
+		s = s.Mul(s, new(big.Int).ModInverse(k, Q))
+		s = s.Mod(s, Q)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+//ElGamalVerify verifies an ElGamal signature (r,s) for message. It checks
+//that 0 < r < p, 0 < s < q and g^H(m) = y^r * r^s (mod p).
+func ElGamalVerify(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) error {
+	z, _, err := hashMessage(message, hash, publicKey.Q)
+	if err != nil {
+		return err
+	}
+	return ElGamalVerifyUnhashed(z, r, s, publicKey)
+}
+
+//ElGamalVerifyUnhashed verifies an ElGamal signature (r,s) over the integer m
+//without hashing it. Signatures checked this way can be forged with
+//ForgeElGamalSignature.
+func ElGamalVerifyUnhashed(m, r, s *big.Int, publicKey *PublicKey) error {
+	P := publicKey.P
+	if r.Sign() <= 0 || r.Cmp(P) >= 0 || s.Sign() <= 0 || s.Cmp(publicKey.Q) >= 0 {
+		return ErrRangeCheck
+	}
+	lhs := new(big.Int).Exp(publicKey.G, m, P)
+	rhs := new(big.Int).Exp(publicKey.Y, r, P)
+	rhs = rhs.Mul(rhs, new(big.Int).Exp(r, s, P))
+	rhs = rhs.Mod(rhs, P)
+	if lhs.Cmp(rhs) != 0 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+//ForgeElGamalSignature produces an existential forgery against unhashed
+//ElGamal signatures without the private key. For random e and v it sets
+//  r = g^e * y^v mod p
+//  s = -r / v (mod q)
+//  m = e * s (mod q)
+//and then y^r * r^s = y^(r + v*s) * g^(e*s) = g^m. The forger does not get to
+//pick m, but with no hash in the way any m is a valid message.
+func ForgeElGamalSignature(publicKey *PublicKey) (m, r, s *big.Int, err error) {
+	pub := &PrivateKey{PublicKey: publicKey}
+	Q := publicKey.Q
+	for {
+		e, err := RandomNonce(nil, pub)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		v, err := RandomNonce(nil, pub)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		r = new(big.Int).Exp(publicKey.G, e, publicKey.P)
+		r = r.Mul(r, new(big.Int).Exp(publicKey.Y, v, publicKey.P))
+		r = r.Mod(r, publicKey.P)
+		s = new(big.Int).ModInverse(v, Q)
+		s = s.Mul(s, r)
+		s = s.Neg(s)
+		s = s.Mod(s, Q)
+		if s.Sign() == 0 {
+			continue
+		}
+		m = new(big.Int).Mul(e, s)
+		return m.Mod(m, Q), r, s, nil
+	}
+}
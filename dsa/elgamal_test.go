@@ -0,0 +1,124 @@
+package dsa
+
+import (
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+func TestElGamalEncryption(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	pub := priv.PublicKey
+
+	m := new(big.Int).SetBytes([]byte("attack at dawn"))
+	ct, err := ElGamalEncrypt(m, pub)
+	if err != nil {
+		t.Errorf("failed to encrypt message")
+		return
+	}
+	pt, err := ElGamalDecrypt(ct, priv)
+	if err != nil || pt.Cmp(m) != 0 {
+		t.Errorf("failed to decrypt message")
+		return
+	}
+
+	//An attacker who only has the ciphertext and the public key doubles the
+	//plaintext without being able to read it.
+	two, err := ElGamalEncrypt(big.NewInt(2), pub)
+	if err != nil {
+		t.Errorf("failed to encrypt factor")
+		return
+	}
+	pt, err = ElGamalDecrypt(ElGamalMul(ct, two, pub), priv)
+	if err != nil || pt.Cmp(new(big.Int).Lsh(m, 1)) != 0 {
+		t.Errorf("tampered ciphertext did not decrypt to 2m")
+		return
+	}
+
+	if _, err = ElGamalEncrypt(pub.P, pub); err == nil {
+		t.Errorf("encrypted a message larger than p")
+		return
+	}
+}
+
+func TestElGamalExponential(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	pub := priv.PublicKey
+
+	//the votes of three voters are tallied without decrypting any ballot
+	votes := []int64{1, 0, 1}
+	var tally *ElGamalCiphertext
+	for _, vote := range votes {
+		ct, err := ElGamalEncryptExponential(big.NewInt(vote), pub)
+		if err != nil {
+			t.Errorf("failed to encrypt vote")
+			return
+		}
+		if tally == nil {
+			tally = ct
+			continue
+		}
+		tally = ElGamalMul(tally, ct, pub)
+	}
+	sum, err := ElGamalDecryptExponential(tally, priv, big.NewInt(1000))
+	if err != nil {
+		t.Errorf("failed to decrypt tally: %v", err)
+		return
+	}
+	if sum.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("unexpected tally %v", sum)
+		return
+	}
+}
+
+func TestElGamalSignature(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("i'm walking here!")
+	r, s, err := ElGamalSign(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if err = ElGamalVerify(message, r, s, crypto.SHA256, priv.PublicKey); err != nil {
+		t.Errorf("failed to verify signature")
+		return
+	}
+	if err = ElGamalVerify([]byte("i'm driving here!"), r, s, crypto.SHA256, priv.PublicKey); err != ErrMismatch {
+		t.Errorf("verified a signature for the wrong message")
+		return
+	}
+}
+
+func TestForgeElGamalSignature(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	m, r, s, err := ForgeElGamalSignature(priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to forge signature")
+		return
+	}
+	if err = ElGamalVerifyUnhashed(m, r, s, priv.PublicKey); err != nil {
+		t.Errorf("forged signature did not verify")
+		return
+	}
+	t.Logf("Forged a signature for m = %x\n", m)
+}
@@ -0,0 +1,260 @@
+package dsa
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+//oidDSA is the id-dsa algorithm identifier from RFC 3279.
+var oidDSA = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
+
+//dsaParameters mirrors the Dss-Parms structure from RFC 3279.
+type dsaParameters struct {
+	P, Q, G *big.Int
+}
+
+//opensslPrivateKey mirrors the DSA private key structure used by OpenSSL's
+//"DSA PRIVATE KEY" PEM blocks.
+type opensslPrivateKey struct {
+	Version       int
+	P, Q, G, Y, X *big.Int
+}
+
+//algorithmIdentifier mirrors the X.509 AlgorithmIdentifier structure.
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+//subjectPublicKeyInfo mirrors the X.509 SubjectPublicKeyInfo structure.
+type subjectPublicKeyInfo struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+//pkcs8PrivateKey mirrors the PKCS#8 PrivateKeyInfo structure.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+//dsaAlgorithm returns the AlgorithmIdentifier for a DSA key with the domain
+//parameters of publicKey.
+func dsaAlgorithm(publicKey *PublicKey) (algorithmIdentifier, error) {
+	params, err := asn1.Marshal(dsaParameters{publicKey.P, publicKey.Q, publicKey.G})
+	if err != nil {
+		return algorithmIdentifier{}, err
+	}
+	return algorithmIdentifier{
+		Algorithm:  oidDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, nil
+}
+
+//parseDSAAlgorithm extracts the domain parameters from a DSA
+//AlgorithmIdentifier.
+func parseDSAAlgorithm(alg algorithmIdentifier) (*dsaParameters, error) {
+	if !alg.Algorithm.Equal(oidDSA) {
+		return nil, fmt.Errorf("key is not a DSA key")
+	}
+	params := new(dsaParameters)
+	rest, err := asn1.Unmarshal(alg.Parameters.FullBytes, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after DSA parameters")
+	}
+	if params.P.Sign() <= 0 || params.Q.Sign() <= 0 || params.G.Sign() <= 0 {
+		return nil, fmt.Errorf("DSA parameters contain a non-positive value")
+	}
+	return params, nil
+}
+
+//MarshalPKIXPublicKey encodes publicKey as an X.509 SubjectPublicKeyInfo in
+//DER form.
+func MarshalPKIXPublicKey(publicKey *PublicKey) ([]byte, error) {
+	alg, err := dsaAlgorithm(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	y, err := asn1.Marshal(publicKey.Y)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: alg,
+		PublicKey: asn1.BitString{Bytes: y, BitLength: 8 * len(y)},
+	})
+}
+
+//ParsePKIXPublicKey decodes an X.509 SubjectPublicKeyInfo in DER form that
+//holds a DSA public key.
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after public key")
+	}
+	params, err := parseDSAAlgorithm(spki.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	y := new(big.Int)
+	rest, err = asn1.Unmarshal(spki.PublicKey.RightAlign(), &y)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after public value")
+	}
+	if y.Sign() <= 0 {
+		return nil, fmt.Errorf("public value is not positive")
+	}
+	return &PublicKey{P: params.P, Q: params.Q, G: params.G, Y: y}, nil
+}
+
+//MarshalOpenSSLPrivateKey encodes privateKey in the DER form OpenSSL uses for
+//"DSA PRIVATE KEY" PEM blocks.
+func MarshalOpenSSLPrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	pub := privateKey.PublicKey
+	return asn1.Marshal(opensslPrivateKey{
+		P: pub.P,
+		Q: pub.Q,
+		G: pub.G,
+		Y: pub.Y,
+		X: privateKey.X,
+	})
+}
+
+//ParseOpenSSLPrivateKey decodes a DSA private key in the DER form OpenSSL uses
+//for "DSA PRIVATE KEY" PEM blocks.
+func ParseOpenSSLPrivateKey(der []byte) (*PrivateKey, error) {
+	var priv opensslPrivateKey
+	rest, err := asn1.Unmarshal(der, &priv)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if priv.Version != 0 {
+		return nil, fmt.Errorf("unsupported private key version %d", priv.Version)
+	}
+	if priv.P.Sign() <= 0 || priv.Q.Sign() <= 0 || priv.G.Sign() <= 0 ||
+		priv.Y.Sign() <= 0 || priv.X.Sign() <= 0 {
+		return nil, fmt.Errorf("private key contains a non-positive value")
+	}
+	return &PrivateKey{
+		PublicKey: &PublicKey{P: priv.P, Q: priv.Q, G: priv.G, Y: priv.Y},
+		X:         priv.X,
+	}, nil
+}
+
+//MarshalPKCS8PrivateKey encodes privateKey as a PKCS#8 PrivateKeyInfo in DER
+//form.
+func MarshalPKCS8PrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	alg, err := dsaAlgorithm(privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	x, err := asn1.Marshal(privateKey.X)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8PrivateKey{
+		Algorithm:  alg,
+		PrivateKey: x,
+	})
+}
+
+//ParsePKCS8PrivateKey decodes a PKCS#8 PrivateKeyInfo in DER form that holds
+//a DSA private key. PKCS#8 does not carry the public value so it is
+//recomputed from x.
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var info pkcs8PrivateKey
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	params, err := parseDSAAlgorithm(info.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	x := new(big.Int)
+	rest, err = asn1.Unmarshal(info.PrivateKey, &x)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private value")
+	}
+	if x.Sign() <= 0 {
+		return nil, fmt.Errorf("private value is not positive")
+	}
+	return &PrivateKey{
+		PublicKey: &PublicKey{
+			P: params.P,
+			Q: params.Q,
+			G: params.G,
+			Y: new(big.Int).Exp(params.G, x, params.P),
+		},
+		X: x,
+	}, nil
+}
+
+//MarshalPublicKeyPEM encodes publicKey as a PEM "PUBLIC KEY" block.
+func MarshalPublicKeyPEM(publicKey *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+//MarshalPrivateKeyPEM encodes privateKey as a PEM "DSA PRIVATE KEY" block.
+func MarshalPrivateKeyPEM(privateKey *PrivateKey) ([]byte, error) {
+	der, err := MarshalOpenSSLPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "DSA PRIVATE KEY", Bytes: der}), nil
+}
+
+//ParsePublicKeyPEM decodes the first PEM block in data, which must be a
+//"PUBLIC KEY" block.
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	return ParsePKIXPublicKey(block.Bytes)
+}
+
+//ParsePrivateKeyPEM decodes the first PEM block in data. Both "DSA PRIVATE
+//KEY" (OpenSSL) and "PRIVATE KEY" (PKCS#8) blocks are accepted.
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	switch block.Type {
+	case "DSA PRIVATE KEY":
+		return ParseOpenSSLPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return ParsePKCS8PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
@@ -0,0 +1,127 @@
+package dsa
+
+import (
+	"crypto"
+	stddsa "crypto/dsa"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"math/big"
+	"testing"
+)
+
+func TestPKIXRoundTripStdlib(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+
+	der, err := MarshalPKIXPublicKey(priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to marshal public key: %v", err)
+		return
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Errorf("stdlib failed to parse public key: %v", err)
+		return
+	}
+	stdPub, ok := parsed.(*stddsa.PublicKey)
+	if !ok {
+		t.Errorf("stdlib did not parse a DSA key")
+		return
+	}
+	if stdPub.P.Cmp(priv.PublicKey.P) != 0 || stdPub.Q.Cmp(priv.PublicKey.Q) != 0 ||
+		stdPub.G.Cmp(priv.PublicKey.G) != 0 || stdPub.Y.Cmp(priv.PublicKey.Y) != 0 {
+		t.Errorf("stdlib public key did not match")
+		return
+	}
+
+	pub, err := ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Errorf("failed to parse public key: %v", err)
+		return
+	}
+	if pub.Y.Cmp(priv.PublicKey.Y) != 0 || pub.P.Cmp(priv.PublicKey.P) != 0 {
+		t.Errorf("parsed public key did not match")
+		return
+	}
+}
+
+func TestPrivateKeyRoundTripStdlib(t *testing.T) {
+
+	var stdPriv stddsa.PrivateKey
+	if err := stddsa.GenerateParameters(&stdPriv.Parameters, rand.Reader, stddsa.L1024N160); err != nil {
+		t.Errorf("failed to generate stdlib parameters")
+		return
+	}
+	if err := stddsa.GenerateKey(&stdPriv, rand.Reader); err != nil {
+		t.Errorf("failed to generate stdlib key")
+		return
+	}
+	priv := &PrivateKey{
+		PublicKey: &PublicKey{P: stdPriv.P, Q: stdPriv.Q, G: stdPriv.G, Y: stdPriv.Y},
+		X:         stdPriv.X,
+	}
+
+	pkcs8, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Errorf("failed to marshal PKCS#8 private key: %v", err)
+		return
+	}
+	openssl, err := MarshalPrivateKeyPEM(priv)
+	if err != nil {
+		t.Errorf("failed to marshal PEM private key: %v", err)
+		return
+	}
+	fromPKCS8, err := ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		t.Errorf("failed to parse PKCS#8 private key: %v", err)
+		return
+	}
+	fromPEM, err := ParsePrivateKeyPEM(openssl)
+	if err != nil {
+		t.Errorf("failed to parse PEM private key: %v", err)
+		return
+	}
+
+	message := []byte("round trip")
+	digest := sha1.Sum(message)
+	for _, parsed := range []*PrivateKey{fromPKCS8, fromPEM} {
+		if parsed.X.Cmp(stdPriv.X) != 0 || parsed.PublicKey.Y.Cmp(stdPriv.Y) != 0 {
+			t.Errorf("parsed private key did not match")
+			return
+		}
+		r, s, err := Sign(message, crypto.SHA1, parsed)
+		if err != nil {
+			t.Errorf("failed to sign message")
+			return
+		}
+		if !stddsa.Verify(&stdPriv.PublicKey, digest[:], r, s) {
+			t.Errorf("stdlib rejected a signature from the parsed key")
+			return
+		}
+	}
+
+	r, s, err := stddsa.Sign(rand.Reader, &stdPriv, digest[:])
+	if err != nil {
+		t.Errorf("stdlib failed to sign message")
+		return
+	}
+	pubPEM, err := MarshalPublicKeyPEM(priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to marshal public key: %v", err)
+		return
+	}
+	pub, err := ParsePublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Errorf("failed to parse public key: %v", err)
+		return
+	}
+	if err = Verify(message, new(big.Int).Set(r), new(big.Int).Set(s), crypto.SHA1, pub); err != nil {
+		t.Errorf("parsed public key rejected a stdlib signature")
+		return
+	}
+}
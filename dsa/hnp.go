@@ -0,0 +1,57 @@
+package dsa
+
+import (
+	"fmt"
+	"math/big"
+
+	badbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+//RecoverKeyFromBiasedNonces recovers the private key from signatures whose
+//nonces have their leakedBits most significant bits set to zero. Each
+//signature gives a Hidden Number Problem relation
+//  k_i = (r_i/s_i) * x + H(m_i)/s_i (mod q), 0 <= k_i < 2^(N-leakedBits)
+//which is solved with a lattice reduction. The attack starts with the fewest
+//signatures that could possibly determine x and adds one signature at a time
+//until a candidate satisfies g^x = y. The number of signatures that were
+//needed is returned in used.
+func RecoverKeyFromBiasedNonces(records []SignatureRecord, publicKey *PublicKey, leakedBits int) (x *big.Int, used int, err error) {
+
+	Q := publicKey.Q
+	N := Q.BitLen()
+	if leakedBits <= 0 || leakedBits >= N {
+		return nil, 0, fmt.Errorf("leaked bits must be within [1, %d)", N)
+	}
+	bound := new(big.Int).Lsh(one, uint(N-leakedBits))
+
+	var ts, us []*big.Int
+	for _, record := range records {
+		w := new(big.Int).ModInverse(record.S, Q)
+		if w == nil {
+			continue
+		}
+		t := new(big.Int).Mul(record.R, w)
+		ts = append(ts, t.Mod(t, Q))
+		u := new(big.Int).Mul(record.Digest, w)
+		us = append(us, u.Mod(u, Q))
+	}
+
+	//every signature leaks about leakedBits bits of x, so fewer than
+	//N/leakedBits signatures can not pin it down.
+	start := (N + leakedBits - 1) / leakedBits
+	if start < 2 {
+		start = 2
+	}
+	for m := start; m <= len(ts); m++ {
+		candidates, err := badbig.HiddenNumberProblem(ts[:m], us[:m], Q, bound)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, candidate := range candidates {
+			if new(big.Int).Exp(publicKey.G, candidate, publicKey.P).Cmp(publicKey.Y) == 0 {
+				return candidate, m, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("private key not found with %d signatures", len(ts))
+}
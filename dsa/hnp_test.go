@@ -0,0 +1,51 @@
+package dsa
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"math/big"
+	"testing"
+)
+
+func TestRecoverKeyFromBiasedNonces(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("error generating keypair")
+		return
+	}
+
+	leaks := []int{16, 8}
+	if testing.Short() {
+		leaks = leaks[:1]
+	}
+	for _, leakedBits := range leaks {
+		var records []SignatureRecord
+		for i := 0; i < 40; i++ {
+			message := []byte{byte(leakedBits), byte(i)}
+			r, s, err := SignWithNonce(message, crypto.SHA1, priv, BiasedNonce(leakedBits))
+			if err != nil {
+				t.Errorf("failed to sign message")
+				return
+			}
+			digest := sha1.Sum(message)
+			records = append(records, SignatureRecord{
+				Message: message,
+				R:       r,
+				S:       s,
+				Digest:  new(big.Int).SetBytes(digest[:]),
+			})
+		}
+
+		x, used, err := RecoverKeyFromBiasedNonces(records, priv.PublicKey, leakedBits)
+		if err != nil {
+			t.Errorf("failed to recover private key with %d leaked bits: %v", leakedBits, err)
+			return
+		}
+		if x.Cmp(priv.X) != 0 {
+			t.Errorf("recovered the wrong private key")
+			return
+		}
+		t.Logf("%d leaked bits per nonce needed %d signatures", leakedBits, used)
+	}
+}
@@ -0,0 +1,155 @@
+package dsa
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+//NonceGenerator returns the per-message key k used to sign the message with
+//the supplied digest. SignWithNonce calls it again if k produces r = 0.
+type NonceGenerator func(digest []byte, privateKey *PrivateKey) (k *big.Int, err error)
+
+//RandomNonce draws k uniformly from [1, q) using crypto/rand.
+func RandomNonce(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+	kbuf := make([]byte, len(privateKey.PublicKey.Q.Bytes()))
+	for {
+		_, err = rand.Read(kbuf)
+		if err != nil {
+			return
+		}
+		k = new(big.Int).SetBytes(kbuf)
+		k = k.Mod(k, privateKey.PublicKey.Q)
+		if k.Sign() != 0 {
+			return
+		}
+	}
+}
+
+//BoundedNonce returns a NonceGenerator that draws k uniformly from [kMin,
+//kMax]. A small range makes the private key recoverable with
+//RecoverKeyFromBoundedNonce.
+func BoundedNonce(kMin, kMax *big.Int) NonceGenerator {
+	return func(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+		if kMin.Sign() <= 0 || kMax.Cmp(privateKey.PublicKey.Q) >= 0 || kMin.Cmp(kMax) > 0 {
+			return nil, fmt.Errorf("nonce range must be within [1, q)")
+		}
+		width := new(big.Int).Sub(kMax, kMin)
+		width = width.Add(width, one)
+		k, err = rand.Int(rand.Reader, width)
+		if err != nil {
+			return
+		}
+		return k.Add(k, kMin), nil
+	}
+}
+
+//BiasedNonce returns a NonceGenerator whose nonces leak their leakedBits most
+//significant bits as zero, i.e. k is drawn uniformly from [1, 2^(N-leakedBits))
+//where N is the bit length of q. A handful of leaked bits across enough
+//signatures makes the private key recoverable with
+//RecoverKeyFromBiasedNonces.
+func BiasedNonce(leakedBits int) NonceGenerator {
+	return func(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+		N := privateKey.PublicKey.Q.BitLen()
+		if leakedBits < 0 || leakedBits >= N {
+			return nil, fmt.Errorf("leaked bits must be within [0, %d)", N)
+		}
+		bound := new(big.Int).Lsh(one, uint(N-leakedBits))
+		for {
+			k, err = rand.Int(rand.Reader, bound)
+			if err != nil {
+				return
+			}
+			if k.Sign() != 0 && k.Cmp(privateKey.PublicKey.Q) < 0 {
+				return
+			}
+		}
+	}
+}
+
+//RFC6979Nonce returns a NonceGenerator that derives k deterministically from
+//the private key and the message digest with the HMAC-DRBG construction from
+//RFC 6979 Section 3.2. hash must be the hash function that produced the
+//digest. Candidates that are out of range or give r = 0 are skipped as
+//described in step h.3, so SignWithNonce never has to ask twice.
+func RFC6979Nonce(hash crypto.Hash) NonceGenerator {
+	return func(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+		if !hash.Available() {
+			return nil, fmt.Errorf("hash function is not available")
+		}
+		if len(digest) != hash.Size() {
+			return nil, fmt.Errorf("digest was not produced by the nonce hash function")
+		}
+		pub := privateKey.PublicKey
+		qlen := pub.Q.BitLen()
+		rlen := (qlen + 7) / 8
+
+		//bits2int keeps the leftmost qlen bits of b.
+		bits2int := func(b []byte) *big.Int {
+			v := new(big.Int).SetBytes(b)
+			if len(b)*8 > qlen {
+				v = v.Rsh(v, uint(len(b)*8-qlen))
+			}
+			return v
+		}
+		int2octets := func(v *big.Int) []byte {
+			return v.FillBytes(make([]byte, rlen))
+		}
+		mac := func(key []byte, parts ...[]byte) []byte {
+			h := hmac.New(hash.New, key)
+			for _, part := range parts {
+				h.Write(part)
+			}
+			return h.Sum(nil)
+		}
+
+		xo := int2octets(privateKey.X)
+		h1 := bits2int(digest)
+		ho := int2octets(h1.Mod(h1, pub.Q))
+
+		V := make([]byte, hash.Size())
+		for i := range V {
+			V[i] = 0x01
+		}
+		K := make([]byte, hash.Size())
+		K = mac(K, V, []byte{0x00}, xo, ho)
+		V = mac(K, V)
+		K = mac(K, V, []byte{0x01}, xo, ho)
+		V = mac(K, V)
+
+		r := new(big.Int)
+		for {
+			var T []byte
+			for len(T) < rlen {
+				V = mac(K, V)
+				T = append(T, V...)
+			}
+			k = bits2int(T)
+			if k.Sign() > 0 && k.Cmp(pub.Q) < 0 {
+				r = r.Exp(pub.G, k, pub.P)
+				if r.Mod(r, pub.Q).Sign() != 0 {
+					return k, nil
+				}
+			}
+			K = mac(K, V, []byte{0x00})
+			V = mac(K, V)
+		}
+	}
+}
+
+//DigestNonce uses the message digest itself as the nonce, k = H(m) mod q.
+//It is deterministic but does not depend on the private key, so k is known to
+//anyone who knows the message. The same message signed under two keys shares
+//a nonce, and any single signature leaks its key through
+//RecoverKeyFromDigestNonce.
+func DigestNonce(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+	k = new(big.Int).SetBytes(digest)
+	k = k.Mod(k, privateKey.PublicKey.Q)
+	if k.Sign() == 0 {
+		return nil, fmt.Errorf("digest is zero mod q")
+	}
+	return k, nil
+}
@@ -0,0 +1,154 @@
+package dsa
+
+import (
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+//hexInt parses a hex test vector.
+func hexInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad test vector")
+	}
+	return v
+}
+
+//rfc6979Key returns the 1024-bit DSA key from RFC 6979 Appendix A.2.1.
+func rfc6979Key() *PrivateKey {
+	pub := &PublicKey{
+		P: hexInt("86F5CA03DCFEB225063FF830A0C769B9DD9D6153AD91D7CE27F787C43278B447" +
+			"E6533B86B18BED6E8A48B784A14C252C5BE0DBF60B86D6385BD2F12FB763ED88" +
+			"73ABFD3F5BA2E0A8C0A59082EAC056935E529DAF7C610467899C77ADEDFC846C" +
+			"881870B7B19B2B58F9BE0521A17002E3BDD6B86685EE90B3D9A1B02B782B1779"),
+		Q: hexInt("996F967F6C8E388D9E28D01E205FBA957A5698B1"),
+		G: hexInt("07B0F92546150B62514BB771E2A0C0CE387F03BDA6C56B505209FF25FD3C133D" +
+			"89BBCD97E904E09114D9A7DEFDEADFC9078EA544D2E401AEECC40BB9FBBF78FD" +
+			"87995A10A1C27CB7789B594BA7EFB5C4326A9FE59A070E136DB77175464ADCA4" +
+			"17BE5DCE2F40D10A46A3A3943F26AB7FD9C0398FF8C76EE0A56826A8A88F1DBD"),
+		Y: hexInt("5DF5E01DED31D0297E274E1691C192FE5868FEF9E19A84776454B100CF16F653" +
+			"92195A38B90523E2542EE61871C0440CB87C322FC4B4D2EC5E1E7EC766E1BE8D" +
+			"4CE935437DC11C3C8FD426338933EBFE739CB3465F4D3668C5E473508253B1E6" +
+			"82F65CBDC4FAE93C2EA212390E54905A86E2223170B44EAA7DA5DD9FFCFB7F3B"),
+	}
+	return &PrivateKey{PublicKey: pub, X: hexInt("411602CB19A6CCC34494D79D98EF1E7ED5AF25F7")}
+}
+
+func TestRFC6979Nonce(t *testing.T) {
+
+	priv := rfc6979Key()
+	tests := []struct {
+		message string
+		hash    crypto.Hash
+		k, r, s string
+	}{
+		{
+			"sample",
+			crypto.SHA1,
+			"7BDB6B0FF756E1BB5D53583EF979082F9AD5BD5B",
+			"2E1A0C2562B2912CAAF89186FB0F42001585DA55",
+			"29EFB6B0AFF2D7A68EB70CA313022253B9A88DF5",
+		},
+		{
+			"test",
+			crypto.SHA1,
+			"5C842DF4F9E344EE09F056838B42C7A17F4A6433",
+			"42AB2052FD43E123F0607F115052A67DCD9C5C77",
+			"183916B0230D45B9931491D4C6B0BD2FB4AAF088",
+		},
+		//SHA-256 digests are longer than q and have to be truncated.
+		{
+			"sample",
+			crypto.SHA256,
+			"519BA0546D0C39202A7D34D7DFA5E760B318BCFB",
+			"81F2F5850BE5BC123C43F71A3033E9384611C545",
+			"4CDD914B65EB6C66A8AAAD27299BEE6B035F5E89",
+		},
+		{
+			"test",
+			crypto.SHA256,
+			"5A67592E8128E03A417B0484410FB72C0B630E1A",
+			"22518C127299B0F6FDC9872B282B9E70D0790812",
+			"6837EC18F150D55DE95B5E29BE7AF5D01E4FE160",
+		},
+	}
+	for i, test := range tests {
+		message := []byte(test.message)
+		h := test.hash.New()
+		h.Write(message)
+		k, err := RFC6979Nonce(test.hash)(h.Sum(nil), priv)
+		if err != nil {
+			t.Errorf("test %d: failed to generate nonce: %v", i, err)
+			return
+		}
+		if k.Cmp(hexInt(test.k)) != 0 {
+			t.Errorf("test %d: unexpected nonce %x", i, k)
+			return
+		}
+		r, s, err := SignWithNonce(message, test.hash, priv, RFC6979Nonce(test.hash))
+		if err != nil {
+			t.Errorf("test %d: failed to sign message", i)
+			return
+		}
+		if r.Cmp(hexInt(test.r)) != 0 || s.Cmp(hexInt(test.s)) != 0 {
+			t.Errorf("test %d: unexpected signature (%x, %x)", i, r, s)
+			return
+		}
+		if err = Verify(message, r, s, test.hash, priv.PublicKey); err != nil {
+			t.Errorf("test %d: failed to verify signature", i)
+			return
+		}
+	}
+}
+
+//TestDigestNonceLeaksKey signs the same message under two keys with a nonce
+//that ignores the key. Both signatures share r and each one gives up its key.
+func TestDigestNonceLeaksKey(t *testing.T) {
+
+	alice, err1 := GenerateKey()
+	bob, err2 := GenerateKey()
+	if err1 != nil || err2 != nil {
+		t.Errorf("error generating keypair")
+		return
+	}
+	message := []byte("wire $1000 to mallory")
+
+	r1, s1, err1 := SignWithNonce(message, crypto.SHA1, alice, DigestNonce)
+	r2, s2, err2 := SignWithNonce(message, crypto.SHA1, bob, DigestNonce)
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if r1.Cmp(r2) != 0 {
+		t.Errorf("signatures under different keys did not share a nonce")
+		return
+	}
+
+	for _, signed := range []struct {
+		priv *PrivateKey
+		r, s *big.Int
+	}{{alice, r1, s1}, {bob, r2, s2}} {
+		x, err := RecoverKeyFromDigestNonce(message, signed.r, signed.s, crypto.SHA1, signed.priv.PublicKey)
+		if err != nil {
+			t.Errorf("failed to recover private key: %v", err)
+			return
+		}
+		if x.Cmp(signed.priv.X) != 0 {
+			t.Errorf("recovered the wrong private key")
+			return
+		}
+	}
+
+	//RFC 6979 nonces depend on the key, so the same message does not share r.
+	r1, _, err1 = SignWithNonce(message, crypto.SHA1, alice, RFC6979Nonce(crypto.SHA1))
+	r2, _, err2 = SignWithNonce(message, crypto.SHA1, bob, RFC6979Nonce(crypto.SHA1))
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if r1.Cmp(r2) == 0 {
+		t.Errorf("RFC 6979 nonces were shared across keys")
+		return
+	}
+}
@@ -0,0 +1,206 @@
+package dsa
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+//Parameters holds a set of DSA domain parameters. Seed and Counter record the
+//domain_parameter_seed and counter from FIPS 186-4 Appendix A.1.1.2 so that
+//the generation of P and Q can be checked by ValidateParameters. Seed is nil
+//when the provenance of P and Q is unknown.
+type Parameters struct {
+	P, Q, G *big.Int
+	Seed    []byte
+	Counter int
+}
+
+//primeRounds is the number of Miller-Rabin rounds used for primality tests.
+//FIPS 186-4 Table C.1 gives the minimum number of rounds for each approved
+//size, and none of them needs more than 64.
+const primeRounds = 64
+
+//validSizes lists the (L, N) pairs approved by FIPS 186-4 Section 4.2.
+var validSizes = map[[2]int]bool{
+	{1024, 160}: true,
+	{2048, 224}: true,
+	{2048, 256}: true,
+	{3072, 256}: true,
+}
+
+//defaultParameters returns the hardcoded parameters set up by init().
+func defaultParameters() *Parameters {
+	return &Parameters{P: p, Q: q, G: g}
+}
+
+//GenerateParameters generates DSA domain parameters with an L bit p and an N
+//bit q. P and Q are generated with the probable prime construction from FIPS
+//186-4 Appendix A.1.1.2 using SHA-256, and G with the unverifiable generator
+//construction from Appendix A.2.1.
+func GenerateParameters(L, N int, rand io.Reader) (params *Parameters, err error) {
+
+	if !validSizes[[2]int{L, N}] {
+		return nil, fmt.Errorf("invalid (L, N) pair (%d, %d)", L, N)
+	}
+
+	seed := make([]byte, N/8)
+	for {
+		if _, err = io.ReadFull(rand, seed); err != nil {
+			return nil, err
+		}
+		Q, ok := generateQ(seed, N)
+		if !ok {
+			continue
+		}
+		P, counter, ok := generateP(seed, Q, L, N, -1)
+		if !ok {
+			continue
+		}
+		params = &Parameters{P: P, Q: Q, Seed: seed, Counter: counter}
+		break
+	}
+
+	params.G, err = generateG(params.P, params.Q)
+	return
+}
+
+//generateQ derives a candidate q from seed. It returns false if the
+//candidate is not prime.
+func generateQ(seed []byte, N int) (Q *big.Int, ok bool) {
+	//U = Hash(domain_parameter_seed) mod 2^(N-1)
+	digest := sha256.Sum256(seed)
+	U := new(big.Int).SetBytes(digest[:])
+	twoN1 := new(big.Int).Lsh(one, uint(N-1))
+	U = U.Mod(U, twoN1)
+
+	//q = 2^(N-1) + U + 1 - (U mod 2)
+	Q = new(big.Int).Add(twoN1, U)
+	Q = Q.Add(Q, one)
+	Q = Q.Sub(Q, big.NewInt(int64(U.Bit(0))))
+	return Q, Q.ProbablyPrime(primeRounds)
+}
+
+//generateP searches for p as described in FIPS 186-4 Appendix A.1.1.2 steps
+//9 through 15. If stopAt is non-negative the search stops at that counter
+//value. The counter of the first prime found is returned.
+func generateP(seed []byte, Q *big.Int, L, N, stopAt int) (P *big.Int, counter int, ok bool) {
+
+	outlen := sha256.Size * 8
+	n := (L+outlen-1)/outlen - 1
+	b := L - 1 - n*outlen
+
+	seedlen := len(seed) * 8
+	seedMod := new(big.Int).Lsh(one, uint(seedlen))
+	seedNum := new(big.Int).SetBytes(seed)
+	twoL1 := new(big.Int).Lsh(one, uint(L-1))
+	twoQ := new(big.Int).Lsh(Q, 1)
+	twoB := new(big.Int).Lsh(one, uint(b))
+
+	offset := 1
+	buf := make([]byte, len(seed))
+	for counter = 0; counter < 4*L; counter++ {
+		if stopAt >= 0 && counter > stopAt {
+			break
+		}
+
+		//W = V_0 + V_1*2^outlen + ... + (V_n mod 2^b)*2^(n*outlen)
+		W := new(big.Int)
+		for j := 0; j <= n; j++ {
+			//V_j = Hash((domain_parameter_seed + offset + j) mod 2^seedlen)
+			v := new(big.Int).Add(seedNum, big.NewInt(int64(offset+j)))
+			v = v.Mod(v, seedMod)
+			v.FillBytes(buf)
+			digest := sha256.Sum256(buf)
+			V := new(big.Int).SetBytes(digest[:])
+			if j == n {
+				V = V.Mod(V, twoB)
+			}
+			W = W.Add(W, V.Lsh(V, uint(j*outlen)))
+		}
+
+		//X = W + 2^(L-1), c = X mod 2q, p = X - (c - 1)
+		X := new(big.Int).Add(W, twoL1)
+		c := new(big.Int).Mod(X, twoQ)
+		P = X.Sub(X, c.Sub(c, one))
+
+		offset += n + 1
+		if P.Cmp(twoL1) < 0 {
+			continue
+		}
+		if P.ProbablyPrime(primeRounds) {
+			return P, counter, true
+		}
+	}
+	return nil, 0, false
+}
+
+//generateG returns a generator of the order q subgroup of Z_p* using the
+//construction from FIPS 186-4 Appendix A.2.1.
+func generateG(P, Q *big.Int) (*big.Int, error) {
+	//e = (p-1)/q, g = h^e mod p for h = 2, 3, ... until g != 1
+	e := new(big.Int).Sub(P, one)
+	e = e.Div(e, Q)
+	pm1 := new(big.Int).Sub(P, one)
+	for h := big.NewInt(2); h.Cmp(pm1) < 0; h = h.Add(h, one) {
+		G := new(big.Int).Exp(h, e, P)
+		if G.Cmp(one) != 0 {
+			return G, nil
+		}
+	}
+	return nil, fmt.Errorf("no generator found")
+}
+
+//ValidateParameters checks that params are honest DSA domain parameters. P
+//and Q must be primes of an approved size with q dividing p-1, and G must
+//generate the order q subgroup. If params carries a seed then P and Q must
+//also be reproducible from it as described in FIPS 186-4 Appendix A.1.1.3.
+func ValidateParameters(params *Parameters) error {
+
+	if params.P == nil || params.Q == nil || params.G == nil {
+		return fmt.Errorf("parameters are incomplete")
+	}
+	L := params.P.BitLen()
+	N := params.Q.BitLen()
+	if !validSizes[[2]int{L, N}] {
+		return fmt.Errorf("invalid (L, N) pair (%d, %d)", L, N)
+	}
+	if !params.Q.ProbablyPrime(primeRounds) {
+		return fmt.Errorf("q is not prime")
+	}
+	if !params.P.ProbablyPrime(primeRounds) {
+		return fmt.Errorf("p is not prime")
+	}
+	pm1 := new(big.Int).Sub(params.P, one)
+	if new(big.Int).Mod(pm1, params.Q).Sign() != 0 {
+		return fmt.Errorf("q does not divide p-1")
+	}
+
+	//FIPS 186-4 Appendix A.2.2: 2 <= g <= p-1 and g^q = 1 (mod p)
+	if params.G.Cmp(big.NewInt(2)) < 0 || params.G.Cmp(pm1) > 0 {
+		return fmt.Errorf("g is out of range")
+	}
+	if new(big.Int).Exp(params.G, params.Q, params.P).Cmp(one) != 0 {
+		return fmt.Errorf("g does not generate the order q subgroup")
+	}
+
+	if params.Seed == nil {
+		return nil
+	}
+	if len(params.Seed)*8 < N {
+		return fmt.Errorf("domain parameter seed is too short")
+	}
+	if params.Counter < 0 || params.Counter >= 4*L {
+		return fmt.Errorf("counter is out of range")
+	}
+	Q, ok := generateQ(params.Seed, N)
+	if !ok || Q.Cmp(params.Q) != 0 {
+		return fmt.Errorf("q was not generated from the seed")
+	}
+	P, counter, ok := generateP(params.Seed, Q, L, N, params.Counter)
+	if !ok || counter != params.Counter || P.Cmp(params.P) != 0 {
+		return fmt.Errorf("p was not generated from the seed")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package dsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestGenerateParameters(t *testing.T) {
+
+	sizes := [][2]int{{1024, 160}}
+	if !testing.Short() {
+		sizes = append(sizes, [2]int{2048, 224}, [2]int{3072, 256})
+	}
+
+	for _, size := range sizes {
+		params, err := GenerateParameters(size[0], size[1], rand.Reader)
+		if err != nil {
+			t.Errorf("failed to generate parameters: %v", err)
+			return
+		}
+		if params.P.BitLen() != size[0] || params.Q.BitLen() != size[1] {
+			t.Errorf("parameters were the wrong size")
+			return
+		}
+		if err = ValidateParameters(params); err != nil {
+			t.Errorf("generated parameters failed validation: %v", err)
+			return
+		}
+
+		priv, err := GenerateKeyWithParameters(params)
+		if err != nil {
+			t.Errorf("failed to generate key")
+			return
+		}
+		if priv.PublicKey.P.Cmp(params.P) != 0 {
+			t.Errorf("key did not carry its parameters")
+			return
+		}
+		message := []byte("i'm walking here!")
+		r, s, err := Sign(message, crypto.SHA1, priv)
+		if err != nil {
+			t.Errorf("failed to sign message")
+			return
+		}
+		if err = Verify(message, r, s, crypto.SHA1, priv.PublicKey); err != nil {
+			t.Errorf("failed to verify signature")
+			return
+		}
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+
+	if err := ValidateParameters(defaultParameters()); err != nil {
+		t.Errorf("hardcoded parameters failed validation: %v", err)
+		return
+	}
+
+	params, err := GenerateParameters(1024, 160, rand.Reader)
+	if err != nil {
+		t.Errorf("failed to generate parameters: %v", err)
+		return
+	}
+
+	tampered := []func(*Parameters){
+		func(p *Parameters) { p.G = big.NewInt(1) },
+		func(p *Parameters) { p.G = new(big.Int).Add(p.P, one) },
+		func(p *Parameters) { p.G = new(big.Int).Sub(p.P, one) },
+		func(p *Parameters) { p.Counter += 1 },
+		func(p *Parameters) {
+			p.Seed = append([]byte{}, p.Seed...)
+			p.Seed[0] ^= 0x01
+		},
+		func(p *Parameters) { p.Q = new(big.Int).Add(p.Q, big.NewInt(2)) },
+	}
+	for i, tamper := range tampered {
+		bad := *params
+		tamper(&bad)
+		if ValidateParameters(&bad) == nil {
+			t.Errorf("tampered parameters %d passed validation", i)
+			return
+		}
+	}
+}
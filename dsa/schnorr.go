@@ -0,0 +1,272 @@
+package dsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+//SchnorrSignature is a Schnorr signature (R, s) with R = g^k mod p and
+//s = k + e*x (mod q), where e = H(R || y || m).
+type SchnorrSignature struct {
+	R, S *big.Int
+}
+
+//schnorrChallenge computes e = H(R || y || message) truncated to the bit
+//length of q and reduced mod q. R and y are encoded as big-endian integers
+//the size of p.
+func schnorrChallenge(R *big.Int, message []byte, hash crypto.Hash, publicKey *PublicKey) (e *big.Int, err error) {
+	size := len(publicKey.P.Bytes())
+	buf := make([]byte, 0, 2*size+len(message))
+	buf = append(buf, R.FillBytes(make([]byte, size))...)
+	buf = append(buf, publicKey.Y.FillBytes(make([]byte, size))...)
+	buf = append(buf, message...)
+	e, _, err = hashMessage(buf, hash, publicKey.Q)
+	if err != nil {
+		return
+	}
+	return e.Mod(e, publicKey.Q), nil
+}
+
+//SchnorrSign signs message with the Schnorr signature scheme over the group of
+//privateKey.
+func SchnorrSign(message []byte, hash crypto.Hash, privateKey *PrivateKey) (sig *SchnorrSignature, err error) {
+	return SchnorrSignWithNonce(message, hash, privateKey, RandomNonce)
+}
+
+//SchnorrSignWithNonce is SchnorrSign with the nonce k drawn from nonce. The
+//nonce generator is passed the digest of message alone.
+func SchnorrSignWithNonce(message []byte, hash crypto.Hash, privateKey *PrivateKey, nonce NonceGenerator) (sig *SchnorrSignature, err error) {
+	_, digest, err := hashMessage(message, hash, privateKey.PublicKey.Q)
+	if err != nil {
+		return
+	}
+	k, err := nonce(digest, privateKey)
+	if err != nil {
+		return
+	}
+	pub := privateKey.PublicKey
+	R := new(big.Int).Exp(pub.G, k, pub.P)
+	e, err := schnorrChallenge(R, message, hash, pub)
+	if err != nil {
+		return
+	}
+	s := new(big.Int).Mul(e, privateKey.X)
+	s = s.Add(s, k)
+	s = s.Mod(s, pub.Q)
+	return &SchnorrSignature{R: R, S: s}, nil
+}
+
+//checkSchnorrSignature returns ErrRangeCheck unless R is an element of the
+//order q subgroup and 0 <= s < q.
+func checkSchnorrSignature(sig *SchnorrSignature, publicKey *PublicKey) error {
+	if sig.R.Sign() <= 0 || sig.R.Cmp(publicKey.P) >= 0 ||
+		sig.S.Sign() < 0 || sig.S.Cmp(publicKey.Q) >= 0 {
+		return ErrRangeCheck
+	}
+	if new(big.Int).Exp(sig.R, publicKey.Q, publicKey.P).Cmp(one) != 0 {
+		return ErrRangeCheck
+	}
+	return nil
+}
+
+//SchnorrVerify verifies a Schnorr signature by checking g^s = R * y^e (mod p).
+//It returns ErrInvalidGenerator for a bad generator and ErrRangeCheck or
+//ErrMismatch like Verify.
+func SchnorrVerify(message []byte, sig *SchnorrSignature, hash crypto.Hash, publicKey *PublicKey) error {
+	if err := checkGenerator(publicKey); err != nil {
+		return err
+	}
+	if err := checkSchnorrSignature(sig, publicKey); err != nil {
+		return err
+	}
+	e, err := schnorrChallenge(sig.R, message, hash, publicKey)
+	if err != nil {
+		return err
+	}
+	lhs := new(big.Int).Exp(publicKey.G, sig.S, publicKey.P)
+	rhs := new(big.Int).Exp(publicKey.Y, e, publicKey.P)
+	rhs = rhs.Mul(rhs, sig.R)
+	rhs = rhs.Mod(rhs, publicKey.P)
+	if lhs.Cmp(rhs) != 0 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+//batchWeightBits is the size of the random weights used by
+//SchnorrBatchVerify. A batch containing an invalid signature passes with
+//probability about 2^-batchWeightBits.
+const batchWeightBits = 128
+
+//SchnorrBatchVerify verifies many Schnorr signatures at once. Each equation
+//g^s_i = R_i * y_i^e_i is raised to a random weight a_i and the results are
+//multiplied together, so the batch costs a single exponentiation of g:
+//  g^(sum a_i*s_i) = prod R_i^a_i * y_i^(a_i*e_i) (mod p)
+//The weights stop an attacker from pairing up invalid signatures whose errors
+//cancel out. All public keys must share the same group. A failed batch does
+//not say which signature was bad.
+func SchnorrBatchVerify(messages [][]byte, sigs []*SchnorrSignature, hash crypto.Hash, publicKeys []*PublicKey) error {
+
+	if len(messages) != len(sigs) || len(sigs) != len(publicKeys) || len(sigs) == 0 {
+		return fmt.Errorf("need the same, non-zero number of messages, signatures and keys")
+	}
+	P, Q, G := publicKeys[0].P, publicKeys[0].Q, publicKeys[0].G
+	if err := checkGenerator(publicKeys[0]); err != nil {
+		return err
+	}
+
+	bound := new(big.Int).Lsh(one, batchWeightBits)
+	exponent := new(big.Int)
+	rhs := big.NewInt(1)
+	for i, sig := range sigs {
+		pub := publicKeys[i]
+		if pub.P.Cmp(P) != 0 || pub.Q.Cmp(Q) != 0 || pub.G.Cmp(G) != 0 {
+			return fmt.Errorf("public keys do not share a group")
+		}
+		if err := checkSchnorrSignature(sig, pub); err != nil {
+			return err
+		}
+		e, err := schnorrChallenge(sig.R, messages[i], hash, pub)
+		if err != nil {
+			return err
+		}
+		a, err := rand.Int(rand.Reader, bound)
+		if err != nil {
+			return err
+		}
+		a = a.Add(a, one)
+
+		exponent = exponent.Add(exponent, new(big.Int).Mul(a, sig.S))
+		rhs = rhs.Mul(rhs, new(big.Int).Exp(sig.R, a, P))
+		ae := e.Mul(e, a)
+		rhs = rhs.Mul(rhs, new(big.Int).Exp(pub.Y, ae.Mod(ae, Q), P))
+		rhs = rhs.Mod(rhs, P)
+	}
+	lhs := new(big.Int).Exp(G, exponent.Mod(exponent, Q), P)
+	if lhs.Cmp(rhs) != 0 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+//SchnorrAggregateKeys combines public keys into a single MuSig aggregate key.
+//With L = H(y_1 || ... || y_n) each key gets the coefficient
+//a_i = H(L || y_i) and the aggregate key is prod y_i^a_i. The coefficients
+//stop a rogue key attack where the last signer picks y_n = g^x / prod y_i and
+//controls the aggregate key alone. The keys must share a group and be passed
+//in the same order by every signer.
+func SchnorrAggregateKeys(publicKeys []*PublicKey, hash crypto.Hash) (aggregate *PublicKey, coefficients []*big.Int, err error) {
+
+	if len(publicKeys) == 0 {
+		return nil, nil, fmt.Errorf("no public keys to aggregate")
+	}
+	first := publicKeys[0]
+	size := len(first.P.Bytes())
+	var L []byte
+	for _, pub := range publicKeys {
+		if pub.P.Cmp(first.P) != 0 || pub.Q.Cmp(first.Q) != 0 || pub.G.Cmp(first.G) != 0 {
+			return nil, nil, fmt.Errorf("public keys do not share a group")
+		}
+		L = append(L, pub.Y.FillBytes(make([]byte, size))...)
+	}
+	_, Lhash, err := hashMessage(L, hash, first.Q)
+	if err != nil {
+		return
+	}
+
+	Y := big.NewInt(1)
+	for _, pub := range publicKeys {
+		a, _, err := hashMessage(append(append([]byte{}, Lhash...), pub.Y.FillBytes(make([]byte, size))...), hash, first.Q)
+		if err != nil {
+			return nil, nil, err
+		}
+		a = a.Mod(a, first.Q)
+		coefficients = append(coefficients, a)
+		Y = Y.Mul(Y, new(big.Int).Exp(pub.Y, a, first.P))
+		Y = Y.Mod(Y, first.P)
+	}
+	aggregate = &PublicKey{P: first.P, Q: first.Q, G: first.G, Y: Y}
+	return aggregate, coefficients, nil
+}
+
+//SchnorrCommit draws a signer's nonce k for a MuSig session and returns it
+//with R_i = g^k. k must stay secret and must never be used for two sessions.
+//Signers should exchange commitments to R_i before revealing them.
+func SchnorrCommit(privateKey *PrivateKey) (k, R *big.Int, err error) {
+	k, err = RandomNonce(nil, privateKey)
+	if err != nil {
+		return
+	}
+	R = new(big.Int).Exp(privateKey.PublicKey.G, k, privateKey.PublicKey.P)
+	return k, R, nil
+}
+
+//SchnorrAggregateNonces multiplies the signers' R_i into the session nonce R.
+func SchnorrAggregateNonces(nonces []*big.Int, aggregate *PublicKey) (R *big.Int) {
+	R = big.NewInt(1)
+	for _, Ri := range nonces {
+		R = R.Mul(R, Ri)
+		R = R.Mod(R, aggregate.P)
+	}
+	return R
+}
+
+//SchnorrPartialSign computes a signer's share s_i = k + e*a*x (mod q) of a
+//MuSig signature, where a is the signer's coefficient from
+//SchnorrAggregateKeys, R is the session nonce from SchnorrAggregateNonces and
+//e is the challenge for R under the aggregate key.
+func SchnorrPartialSign(message []byte, hash crypto.Hash, privateKey *PrivateKey, coefficient, k, R *big.Int, aggregate *PublicKey) (s *big.Int, err error) {
+	e, err := schnorrChallenge(R, message, hash, aggregate)
+	if err != nil {
+		return
+	}
+	s = new(big.Int).Mul(e, coefficient)
+	s = s.Mul(s, privateKey.X)
+	s = s.Add(s, k)
+	return s.Mod(s, aggregate.Q), nil
+}
+
+//SchnorrCombine adds the partial signatures into a Schnorr signature that
+//verifies under the aggregate key with SchnorrVerify.
+func SchnorrCombine(R *big.Int, partials []*big.Int, aggregate *PublicKey) *SchnorrSignature {
+	s := new(big.Int)
+	for _, si := range partials {
+		s = s.Add(s, si)
+	}
+	return &SchnorrSignature{R: new(big.Int).Set(R), S: s.Mod(s, aggregate.Q)}
+}
+
+//RecoverSchnorrKeyFromReusedNonce recovers the private key from two Schnorr
+//signatures over different messages that share a nonce. Both signatures have
+//the same R, and subtracting s1 = k + e1*x from s2 = k + e2*x gives
+//  x = (s1 - s2) / (e1 - e2) (mod q)
+//This is the Schnorr counterpart of RecoverPrivateKeyFromSubKey.
+func RecoverSchnorrKeyFromReusedNonce(message1 []byte, sig1 *SchnorrSignature, message2 []byte, sig2 *SchnorrSignature, hash crypto.Hash, publicKey *PublicKey) (x *big.Int, err error) {
+
+	if sig1.R.Cmp(sig2.R) != 0 {
+		return nil, fmt.Errorf("signatures do not share a nonce")
+	}
+	Q := publicKey.Q
+	e1, err := schnorrChallenge(sig1.R, message1, hash, publicKey)
+	if err != nil {
+		return
+	}
+	e2, err := schnorrChallenge(sig2.R, message2, hash, publicKey)
+	if err != nil {
+		return
+	}
+	de := new(big.Int).Sub(e1, e2)
+	de = de.Mod(de, Q)
+	if de.Sign() == 0 {
+		return nil, fmt.Errorf("signatures have the same challenge")
+	}
+	x = new(big.Int).Sub(sig1.S, sig2.S)
+	x = x.Mul(x, de.ModInverse(de, Q))
+	x = x.Mod(x, Q)
+	if new(big.Int).Exp(publicKey.G, x, publicKey.P).Cmp(publicKey.Y) != 0 {
+		return nil, fmt.Errorf("recovered key does not match the public key")
+	}
+	return x, nil
+}
@@ -0,0 +1,155 @@
+package dsa
+
+import (
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+func TestSchnorrSign(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("i'm walking here!")
+	sig, err := SchnorrSign(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if err = SchnorrVerify(message, sig, crypto.SHA256, priv.PublicKey); err != nil {
+		t.Errorf("failed to verify signature")
+		return
+	}
+	if err = SchnorrVerify([]byte("i'm driving here!"), sig, crypto.SHA256, priv.PublicKey); err != ErrMismatch {
+		t.Errorf("verified a signature for the wrong message")
+		return
+	}
+	//R must be in the order q subgroup
+	bad := &SchnorrSignature{R: new(big.Int).Sub(priv.PublicKey.P, one), S: sig.S}
+	if err = SchnorrVerify(message, bad, crypto.SHA256, priv.PublicKey); err != ErrRangeCheck {
+		t.Errorf("accepted R outside of the subgroup")
+		return
+	}
+}
+
+func TestSchnorrBatchVerify(t *testing.T) {
+
+	var messages [][]byte
+	var sigs []*SchnorrSignature
+	var pubs []*PublicKey
+	for i := 0; i < 8; i++ {
+		priv, err := GenerateKey()
+		if err != nil {
+			t.Errorf("failed to generate key")
+			return
+		}
+		message := []byte{byte(i)}
+		sig, err := SchnorrSign(message, crypto.SHA256, priv)
+		if err != nil {
+			t.Errorf("failed to sign message")
+			return
+		}
+		messages = append(messages, message)
+		sigs = append(sigs, sig)
+		pubs = append(pubs, priv.PublicKey)
+	}
+	if err := SchnorrBatchVerify(messages, sigs, crypto.SHA256, pubs); err != nil {
+		t.Errorf("failed to verify batch: %v", err)
+		return
+	}
+
+	//Shifting s by d in one signature and by -d in another keeps the sum of
+	//the s values the same. The random weights still catch it.
+	Q := pubs[0].Q
+	d := big.NewInt(31337)
+	s0 := new(big.Int).Add(sigs[0].S, d)
+	s1 := new(big.Int).Sub(sigs[1].S, d)
+	sigs[0] = &SchnorrSignature{R: sigs[0].R, S: s0.Mod(s0, Q)}
+	sigs[1] = &SchnorrSignature{R: sigs[1].R, S: s1.Mod(s1, Q)}
+	if err := SchnorrBatchVerify(messages, sigs, crypto.SHA256, pubs); err != ErrMismatch {
+		t.Errorf("verified a batch with invalid signatures")
+		return
+	}
+}
+
+func TestSchnorrMuSig(t *testing.T) {
+
+	alice, err1 := GenerateKey()
+	bob, err2 := GenerateKey()
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("2-of-2 spend")
+	hash := crypto.SHA256
+
+	aggregate, coefficients, err := SchnorrAggregateKeys([]*PublicKey{alice.PublicKey, bob.PublicKey}, hash)
+	if err != nil {
+		t.Errorf("failed to aggregate keys")
+		return
+	}
+	kA, RA, err1 := SchnorrCommit(alice)
+	kB, RB, err2 := SchnorrCommit(bob)
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to commit to nonces")
+		return
+	}
+	R := SchnorrAggregateNonces([]*big.Int{RA, RB}, aggregate)
+	sA, err1 := SchnorrPartialSign(message, hash, alice, coefficients[0], kA, R, aggregate)
+	sB, err2 := SchnorrPartialSign(message, hash, bob, coefficients[1], kB, R, aggregate)
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to produce partial signatures")
+		return
+	}
+	sig := SchnorrCombine(R, []*big.Int{sA, sB}, aggregate)
+	if err = SchnorrVerify(message, sig, hash, aggregate); err != nil {
+		t.Errorf("aggregate signature did not verify")
+		return
+	}
+
+	//a single signer's share is not enough
+	partial := SchnorrCombine(R, []*big.Int{sA}, aggregate)
+	if SchnorrVerify(message, partial, hash, aggregate) == nil {
+		t.Errorf("a single partial signature verified")
+		return
+	}
+}
+
+func TestRecoverSchnorrKeyFromReusedNonce(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	k := big.NewInt(31337)
+	fixed := func(digest []byte, privateKey *PrivateKey) (*big.Int, error) {
+		return k, nil
+	}
+	m1, m2 := []byte("first"), []byte("second")
+	sig1, err1 := SchnorrSignWithNonce(m1, crypto.SHA256, priv, fixed)
+	sig2, err2 := SchnorrSignWithNonce(m2, crypto.SHA256, priv, fixed)
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	x, err := RecoverSchnorrKeyFromReusedNonce(m1, sig1, m2, sig2, crypto.SHA256, priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to recover private key: %v", err)
+		return
+	}
+	if x.Cmp(priv.X) != 0 {
+		t.Errorf("recovered the wrong private key")
+		return
+	}
+
+	//fresh nonces leak nothing
+	sig2, _ = SchnorrSign(m2, crypto.SHA256, priv)
+	if _, err = RecoverSchnorrKeyFromReusedNonce(m1, sig1, m2, sig2, crypto.SHA256, priv.PublicKey); err == nil {
+		t.Errorf("recovered a key without nonce reuse")
+		return
+	}
+}
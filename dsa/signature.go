@@ -0,0 +1,113 @@
+package dsa
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+)
+
+//dsaSignature mirrors the Dss-Sig-Value structure from RFC 3279.
+type dsaSignature struct {
+	R, S *big.Int
+}
+
+//MarshalSignature encodes the signature (r,s) as a DER Dss-Sig-Value.
+func MarshalSignature(r, s *big.Int) ([]byte, error) {
+	if r.Sign() <= 0 || s.Sign() <= 0 {
+		return nil, fmt.Errorf("signature values must be positive")
+	}
+	return asn1.Marshal(dsaSignature{r, s})
+}
+
+//ParseSignature parses a DER encoded Dss-Sig-Value. It only accepts the
+//unique DER encoding of a signature: lengths and integers must be minimally
+//encoded, r and s must be positive and nothing may follow the sequence.
+func ParseSignature(der []byte) (r, s *big.Int, err error) {
+	var sig dsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("trailing data after signature")
+	}
+	//encoding/asn1 is mostly strict already. Re-encoding catches anything it
+	//let through.
+	canonical, err := MarshalSignature(sig.R, sig.S)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(canonical, der) {
+		return nil, nil, fmt.Errorf("signature is not DER encoded")
+	}
+	return sig.R, sig.S, nil
+}
+
+//ParseSignatureLax parses a BER encoded Dss-Sig-Value the way many lenient
+//parsers do. It accepts long form and zero padded lengths, integers with
+//redundant leading bytes and trailing data both inside and after the
+//sequence. Several distinct byte strings therefore parse to the same (r,s),
+//which breaks any deduplication that keys on the encoded signature.
+func ParseSignatureLax(ber []byte) (r, s *big.Int, err error) {
+	tag, body, _, err := readLaxTLV(ber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag != 0x30 {
+		return nil, nil, fmt.Errorf("signature is not a sequence")
+	}
+	values := make([]*big.Int, 2)
+	for i := range values {
+		var content []byte
+		tag, content, body, err = readLaxTLV(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if tag != 0x02 || len(content) == 0 {
+			return nil, nil, fmt.Errorf("signature value is not an integer")
+		}
+		values[i] = new(big.Int).SetBytes(content)
+		if content[0]&0x80 != 0 {
+			//two's complement negative
+			values[i] = values[i].Sub(values[i], new(big.Int).Lsh(one, uint(len(content)*8)))
+		}
+	}
+	return values[0], values[1], nil
+}
+
+//readLaxTLV reads a single tag, length and value from data. Long form lengths
+//are accepted even when the short form would do.
+func readLaxTLV(data []byte) (tag byte, content, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated encoding")
+	}
+	tag = data[0]
+	length := int(data[1])
+	data = data[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < n {
+			return 0, nil, nil, fmt.Errorf("unsupported length encoding")
+		}
+		length = 0
+		for _, b := range data[:n] {
+			length = length<<8 | int(b)
+		}
+		data = data[n:]
+	}
+	if length > len(data) {
+		return 0, nil, nil, fmt.Errorf("truncated encoding")
+	}
+	return tag, data[:length], data[length:], nil
+}
+
+//MalleateSignature returns the twin (r, q-s) of the signature (r,s). This is
+//the classic ECDSA malleation, where the twin always verifies because r only
+//depends on the x-coordinate of kG and -kG shares it. Finite field DSA has no
+//such symmetry: negating s negates k and (g^-k mod p) mod q is unrelated to r,
+//so Verify rejects the twin. Deduplication of DSA signatures still has to deal
+//with encoding malleability, see ParseSignatureLax.
+func MalleateSignature(r, s *big.Int, publicKey *PublicKey) (r2, s2 *big.Int) {
+	return new(big.Int).Set(r), new(big.Int).Sub(publicKey.Q, s)
+}
@@ -0,0 +1,109 @@
+package dsa
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+)
+
+//berTLV encodes a tag and value, using a two byte long form length when
+//longForm is set even though the short form would do.
+func berTLV(tag byte, content []byte, longForm bool) []byte {
+	if longForm {
+		return append([]byte{tag, 0x82, byte(len(content) >> 8), byte(len(content))}, content...)
+	}
+	return append([]byte{tag, byte(len(content))}, content...)
+}
+
+func TestSignatureEncoding(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("i'm walking here!")
+	r, s, err := Sign(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+
+	der, err := MarshalSignature(r, s)
+	if err != nil {
+		t.Errorf("failed to marshal signature")
+		return
+	}
+	r2, s2, err := ParseSignature(der)
+	if err != nil || r2.Cmp(r) != 0 || s2.Cmp(s) != 0 {
+		t.Errorf("failed to round trip signature")
+		return
+	}
+
+	//der is short enough for a short form length, so its body starts at 2.
+	body := der[2:]
+	rDER, _ := asn1.Marshal(r)
+	padded := berTLV(0x02, append([]byte{0, 0}, s.Bytes()...), false)
+
+	variants := map[string][]byte{
+		"long form length":     berTLV(0x30, body, true),
+		"padded integer":       berTLV(0x30, append(append([]byte{}, rDER...), padded...), false),
+		"trailing data":        append(berTLV(0x30, body, false), 0xde, 0xad),
+		"data inside sequence": berTLV(0x30, append(append([]byte{}, body...), 0x05, 0x00), false),
+	}
+	seen := map[string]bool{string(der): true}
+	for name, variant := range variants {
+		if _, _, err = ParseSignature(variant); err == nil {
+			t.Errorf("strict parser accepted %s", name)
+			return
+		}
+		r2, s2, err = ParseSignatureLax(variant)
+		if err != nil {
+			t.Errorf("lax parser rejected %s: %v", name, err)
+			return
+		}
+		if err = Verify(message, r2, s2, crypto.SHA256, priv.PublicKey); err != nil {
+			t.Errorf("%s did not verify", name)
+			return
+		}
+		//every variant slips past deduplication keyed on the raw bytes
+		if seen[string(variant)] {
+			t.Errorf("%s collided with an earlier encoding", name)
+			return
+		}
+		seen[string(variant)] = true
+	}
+}
+
+func TestMalleateSignature(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("i'm walking here!")
+	r, s, err := Sign(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+
+	r2, s2 := MalleateSignature(r, s, priv.PublicKey)
+	if r2.Cmp(r) != 0 || new(big.Int).Add(s, s2).Cmp(priv.PublicKey.Q) != 0 {
+		t.Errorf("twin is not (r, q-s)")
+		return
+	}
+	der1, _ := MarshalSignature(r, s)
+	der2, _ := MarshalSignature(r2, s2)
+	if string(der1) == string(der2) {
+		t.Errorf("twin has the same encoding")
+		return
+	}
+	//Unlike ECDSA, negating s does not give another valid DSA signature.
+	if err = Verify(message, r2, s2, crypto.SHA256, priv.PublicKey); err != ErrMismatch {
+		t.Errorf("expected the twin to be rejected, got %v", err)
+		return
+	}
+}
@@ -0,0 +1,38 @@
+package dsa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+//ForgeMagicSignature forges a "magic" signature for publicKey that is valid
+//for every message when the generator has been tampered with so that g = p+1
+//(or any g = 1 mod p). Every power of such a g is 1, so verification reduces
+//to checking r = (y^(r/s) mod p) mod q, which holds for
+//  r = (y^z mod p) mod q
+//  s = r/z mod q
+//for any z.
+func ForgeMagicSignature(publicKey *PublicKey) (r, s *big.Int, err error) {
+	for {
+		z, err := rand.Int(rand.Reader, publicKey.Q)
+		if err != nil {
+			return nil, nil, err
+		}
+		if z.Sign() == 0 {
+			continue
+		}
+		r = new(big.Int).Exp(publicKey.Y, z, publicKey.P)
+		r = r.Mod(r, publicKey.Q)
+		if r.Sign() == 0 {
+			continue
+		}
+		zinv := new(big.Int).ModInverse(z, publicKey.Q)
+		if zinv == nil {
+			return nil, nil, fmt.Errorf("q is not prime")
+		}
+		s = new(big.Int).Mul(r, zinv)
+		s = s.Mod(s, publicKey.Q)
+		return r, s, nil
+	}
+}
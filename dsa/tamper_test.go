@@ -0,0 +1,70 @@
+package dsa
+
+import (
+	"crypto"
+	"math/big"
+	"testing"
+)
+
+//TestGeneratorZero is a test for the first half of Cryptopals Set 6
+//Challenge 45.
+func TestGeneratorZero(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	pub := *priv.PublicKey
+	pub.G = big.NewInt(0)
+	pub.Y = big.NewInt(0)
+
+	//With g = 0 every r is 0, so (0, s) verifies for any message.
+	r := big.NewInt(0)
+	s := big.NewInt(12345)
+	for _, message := range []string{"Hello, world", "Goodbye, world"} {
+		if err = VerifyUnsafe([]byte(message), r, s, crypto.SHA1, &pub); err != nil {
+			t.Errorf("lenient verifier rejected the g = 0 signature")
+			return
+		}
+		if err = Verify([]byte(message), r, s, crypto.SHA1, &pub); err != ErrInvalidGenerator {
+			t.Errorf("strict verifier did not reject the g = 0 signature: %v", err)
+			return
+		}
+	}
+}
+
+//TestMagicSignature is a test for the second half of Cryptopals Set 6
+//Challenge 45.
+func TestMagicSignature(t *testing.T) {
+
+	priv, err := GenerateKey()
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	pub := *priv.PublicKey
+	pub.G = new(big.Int).Add(pub.P, one)
+
+	r, s, err := ForgeMagicSignature(&pub)
+	if err != nil {
+		t.Errorf("failed to forge signature")
+		return
+	}
+	for _, message := range []string{"Hello, world", "Goodbye, world"} {
+		if err = VerifyUnsafe([]byte(message), r, s, crypto.SHA1, &pub); err != nil {
+			t.Errorf("lenient verifier rejected the magic signature")
+			return
+		}
+		if err = Verify([]byte(message), r, s, crypto.SHA1, &pub); err != ErrInvalidGenerator {
+			t.Errorf("strict verifier did not reject the magic signature: %v", err)
+			return
+		}
+	}
+
+	//The magic signature is worthless against the honest generator.
+	if Verify([]byte("Hello, world"), r, s, crypto.SHA1, priv.PublicKey) == nil {
+		t.Errorf("magic signature verified under the honest generator")
+		return
+	}
+}
@@ -0,0 +1,78 @@
+package elliptic
+
+import (
+	"fmt"
+	"math/big"
+)
+
+//ValidationPolicy selects how ECDH checks the peer's public point before
+//using it.
+type ValidationPolicy int
+
+const (
+	//NoValidation uses the peer's point as is. This is what the invalid
+	//curve attack in pohligHellmanOnline exploits.
+	NoValidation ValidationPolicy = iota
+	//OnCurveValidation checks that the point is a point on the curve other
+	//than the point at infinity.
+	OnCurveValidation
+	//SubgroupValidation also checks that n*P = 0 where n is the order of the
+	//base point, which rules out small subgroup attacks on curves with a
+	//cofactor.
+	SubgroupValidation
+)
+
+//InvalidPointError is returned by ECDH when the peer's point fails the
+//validation policy.
+type InvalidPointError struct {
+	Policy ValidationPolicy
+	Reason string
+}
+
+func (e *InvalidPointError) Error() string {
+	return fmt.Sprintf("invalid peer point: %s", e.Reason)
+}
+
+//ValidatePoint checks (x, y) according to policy. It returns an
+//*InvalidPointError if the point is rejected. SubgroupValidation rejects every
+//point, with an *InvalidPointError, if the curve has no order N to check
+//against.
+func (curve shortWeierstrassCurve) ValidatePoint(x, y *big.Int, policy ValidationPolicy) error {
+
+	if policy == NoValidation {
+		return nil
+	}
+	invalid := func(reason string) error {
+		return &InvalidPointError{Policy: policy, Reason: reason}
+	}
+	if x.Sign() < 0 || y.Sign() < 0 || x.Cmp(curve.P) >= 0 || y.Cmp(curve.P) >= 0 {
+		return invalid("coordinates out of range")
+	}
+	if curve.isZeroPoint(x, y) {
+		return invalid("point at infinity")
+	}
+	if !curve.IsOnCurve(x, y) {
+		return invalid("point is not on the curve")
+	}
+	if policy == OnCurveValidation {
+		return nil
+	}
+	if curve.N == nil || curve.N.Sign() == 0 {
+		return invalid("order of the base point not supplied")
+	}
+	if nx, ny := curve.ScalarMult(x, y, curve.N.Bytes()); !curve.isZeroPoint(nx, ny) {
+		return invalid("point is not in the subgroup generated by the base point")
+	}
+	return nil
+}
+
+//ECDH computes the shared point d*(x, y) for the private key d and the peer's
+//public point (x, y) after validating the point according to policy.
+func ECDH(privateKey *PrivateKey, x, y *big.Int, policy ValidationPolicy) (sx, sy *big.Int, err error) {
+	curve := privateKey.PublicKey.Curve
+	if err = curve.ValidatePoint(x, y, policy); err != nil {
+		return nil, nil, err
+	}
+	sx, sy = curve.ScalarMult(x, y, privateKey.D.Bytes())
+	return sx, sy, nil
+}
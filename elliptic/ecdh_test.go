@@ -0,0 +1,109 @@
+package elliptic
+
+import (
+	"math/big"
+	"testing"
+)
+
+var policies = []ValidationPolicy{NoValidation, OnCurveValidation, SubgroupValidation}
+
+func TestECDHPolicies(t *testing.T) {
+
+	alice, _ := GenerateKey(curve)
+	bob, _ := GenerateKey(curve)
+	for _, policy := range policies {
+		ax, ay, err := ECDH(alice, bob.PublicKey.X, bob.PublicKey.Y, policy)
+		if err != nil {
+			t.Errorf("policy %d: unexpected error: %v", policy, err)
+			return
+		}
+		bx, by, err := ECDH(bob, alice.PublicKey.X, alice.PublicKey.Y, policy)
+		if err != nil {
+			t.Errorf("policy %d: unexpected error: %v", policy, err)
+			return
+		}
+		if !curve.PointEquals(ax, ay, bx, by) {
+			t.Errorf("policy %d: shared secrets did not match", policy)
+			return
+		}
+	}
+}
+
+func TestECDHSmallSubgroup(t *testing.T) {
+
+	//the Cryptopals curve has order 8*n, so n*P has order dividing 8
+	var x, y *big.Int
+	for {
+		x, y = curve.randomPoint()
+		x, y = curve.ScalarMult(x, y, order.Bytes())
+		if !curve.isZeroPoint(x, y) {
+			break
+		}
+	}
+	if err := curve.ValidatePoint(x, y, OnCurveValidation); err != nil {
+		t.Errorf("on-curve validation rejected a point on the curve: %v", err)
+		return
+	}
+	err := curve.ValidatePoint(x, y, SubgroupValidation)
+	if _, ok := err.(*InvalidPointError); !ok {
+		t.Errorf("expected an *InvalidPointError for a small order point, got %v", err)
+		return
+	}
+
+	//without an order there is nothing to check the subgroup against
+	noOrder := NewCurve(a, b, p, zero, gx, gy)
+	err = noOrder.ValidatePoint(gx, gy, SubgroupValidation)
+	if _, ok := err.(*InvalidPointError); !ok {
+		t.Errorf("expected an *InvalidPointError for a curve without an order, got %v", err)
+		return
+	}
+}
+
+func TestECDHInvalidCurveAttack(t *testing.T) {
+
+	b1 := big.NewInt(210)
+	o1, _ := new(big.Int).SetString("233970423115425145550826547352470124412", 10)
+	b2 := big.NewInt(504)
+	o2, _ := new(big.Int).SetString("233970423115425145544350131142039591210", 10)
+	b3 := big.NewInt(727)
+	o3, _ := new(big.Int).SetString("233970423115425145545378039958152057148", 10)
+	smallOrderCurves := []shortWeierstrassCurve{
+		NewCurve(a, b1, p, o1, gx, gy),
+		NewCurve(a, b2, p, o2, gx, gy),
+		NewCurve(a, b3, p, o3, gx, gy),
+	}
+
+	victim, _ := GenerateKey(curve)
+	for _, policy := range policies {
+		var rejected error
+		oracle := func(x, y *big.Int) (*big.Int, *big.Int) {
+			sx, sy, err := ECDH(victim, x, y, policy)
+			if err != nil {
+				rejected = err
+				return big.NewInt(0), big.NewInt(1)
+			}
+			return sx, sy
+		}
+		index, modulus, err := curve.pohligHellmanOnline(smallOrderCurves, oracle)
+
+		if policy == NoValidation {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if index.Cmp(new(big.Int).Mod(victim.D, modulus)) != 0 {
+				t.Errorf("failed to recover the private key without validation")
+				return
+			}
+			continue
+		}
+		if _, ok := rejected.(*InvalidPointError); !ok {
+			t.Errorf("policy %d: expected an *InvalidPointError, got %v", policy, rejected)
+			return
+		}
+		if err == nil && index.Cmp(new(big.Int).Mod(victim.D, modulus)) == 0 {
+			t.Errorf("policy %d: recovered the private key", policy)
+			return
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package elliptic
+
+import (
+	"crypto"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+//ECDSANonceGenerator returns the per-message key k used by
+//ECDSASignWithNonce. digest is the full message digest.
+type ECDSANonceGenerator func(digest []byte, privateKey *PrivateKey) (k *big.Int, err error)
+
+//ErrRangeCheck is returned by ECDSAVerify when r or s is not in [1, n).
+var ErrRangeCheck = errors.New("ecdsa: signature value out of range")
+
+//ErrMismatch is returned by ECDSAVerify when a well-formed signature does not
+//match the message and public key.
+var ErrMismatch = errors.New("ecdsa: signature does not match")
+
+//ECDSARandomNonce returns a random k in [1, n). It is the nonce used by
+//ECDSASign.
+func ECDSARandomNonce(digest []byte, privateKey *PrivateKey) (k *big.Int, err error) {
+	N := privateKey.PublicKey.Curve.N
+	for {
+		k, err = rand.Int(rand.Reader, N)
+		if err != nil || k.Sign() != 0 {
+			return
+		}
+	}
+}
+
+//hashToInt hashes message with hash and converts the leftmost bits of the
+//digest to an integer, keeping as many bits as n has. The full digest is
+//returned as well.
+func hashToInt(message []byte, hash crypto.Hash, N *big.Int) (z *big.Int, digest []byte, err error) {
+	if !hash.Available() {
+		return nil, nil, fmt.Errorf("hash function is not available")
+	}
+	h := hash.New()
+	h.Write(message)
+	digest = h.Sum(nil)
+	z = new(big.Int).SetBytes(digest)
+	if excess := len(digest)*8 - N.BitLen(); excess > 0 {
+		z = z.Rsh(z, uint(excess))
+	}
+	return z, digest, nil
+}
+
+//ECDSASign signs message using the supplied private key and hash function.
+func ECDSASign(message []byte, hash crypto.Hash, privateKey *PrivateKey) (r, s *big.Int, err error) {
+	return ECDSASignWithNonce(message, hash, privateKey, ECDSARandomNonce)
+}
+
+//ECDSASignWithNonce signs message using the supplied private key and hash
+//function and draws the per-message key k from nonce:
+//  r = x(k*G) mod n
+//  s = (H(m) + r*d) / k mod n
+func ECDSASignWithNonce(message []byte, hash crypto.Hash, privateKey *PrivateKey, nonce ECDSANonceGenerator) (r, s *big.Int, err error) {
+
+	curve := privateKey.PublicKey.Curve
+	N := curve.N
+	z, digest, err := hashToInt(message, hash, N)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		k, err := nonce(digest, privateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		k = k.Mod(k, N)
+		if k.Sign() == 0 {
+			continue
+		}
+		rx, _ := curve.ScalarBaseMult(k.Bytes())
+		r = new(big.Int).Mod(rx, N)
+		if r.Sign() == 0 {
+			continue
+		}
+		s = new(big.Int).Mul(r, privateKey.D)
+		s = s.Add(s, z)
+		s = s.Mul(s, new(big.Int).ModInverse(k, N))
+		s = s.Mod(s, N)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+//ECDSAVerify verifies a signature (r,s) for message under the supplied
+//publicKey and hash function. It returns ErrRangeCheck if r or s is not in
+//[1, n) and ErrMismatch if the signature does not match.
+func ECDSAVerify(message []byte, r, s *big.Int, hash crypto.Hash, publicKey *PublicKey) error {
+
+	curve := publicKey.Curve
+	N := curve.N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(N) >= 0 || s.Cmp(N) >= 0 {
+		return ErrRangeCheck
+	}
+	z, _, err := hashToInt(message, hash, N)
+	if err != nil {
+		return err
+	}
+	w := new(big.Int).ModInverse(s, N)
+	u1 := new(big.Int).Mul(z, w)
+	u1 = u1.Mod(u1, N)
+	u2 := new(big.Int).Mul(r, w)
+	u2 = u2.Mod(u2, N)
+	x1, y1 := curve.ScalarBaseMult(u1.Bytes())
+	x2, y2 := curve.ScalarMult(publicKey.X, publicKey.Y, u2.Bytes())
+	x, y := curve.Add(x1, y1, x2, y2)
+	if curve.isZeroPoint(x, y) {
+		return ErrMismatch
+	}
+	if x.Mod(x, N).Cmp(r) != 0 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+//checkPrivateKey returns d if d*G is the public key.
+func checkPrivateKey(d *big.Int, publicKey *PublicKey) (*big.Int, error) {
+	x, y := publicKey.Curve.ScalarBaseMult(d.Bytes())
+	if !publicKey.Curve.PointEquals(x, y, publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("recovered key does not match the public key")
+	}
+	return d, nil
+}
+
+//RecoverKeyFromReusedNonce recovers the private key from two signatures over
+//different messages that were made with the same nonce k. Both signatures
+//share r and
+//  k = (H(m1) - H(m2)) / (s1 - s2) (mod n)
+//  d = (s1*k - H(m1)) / r (mod n)
+func RecoverKeyFromReusedNonce(message1 []byte, r1, s1 *big.Int, message2 []byte, r2, s2 *big.Int, hash crypto.Hash, publicKey *PublicKey) (d *big.Int, err error) {
+
+	N := publicKey.Curve.N
+	if r1.Cmp(r2) != 0 {
+		return nil, fmt.Errorf("signatures do not share a nonce")
+	}
+	z1, _, err := hashToInt(message1, hash, N)
+	if err != nil {
+		return nil, err
+	}
+	z2, _, err := hashToInt(message2, hash, N)
+	if err != nil {
+		return nil, err
+	}
+	ds := new(big.Int).Sub(s1, s2)
+	ds = ds.Mod(ds, N)
+	if ds.Sign() == 0 {
+		return nil, fmt.Errorf("signatures are identical")
+	}
+	k := new(big.Int).Sub(z1, z2)
+	k = k.Mul(k, ds.ModInverse(ds, N))
+	k = k.Mod(k, N)
+
+	d = new(big.Int).Mul(s1, k)
+	d = d.Sub(d, z1)
+	d = d.Mul(d, new(big.Int).ModInverse(r1, N))
+	d = d.Mod(d, N)
+	return checkPrivateKey(d, publicKey)
+}
+
+//RecoverKeyFromRelatedNonces recovers the private key from two signatures
+//whose nonces are related by k2 = a*k1 + b (mod n), such as the output of a
+//linear congruential generator. Substituting k_i = (H(m_i) + r_i*d) / s_i
+//into the relation gives
+//  d = (s1*H(m2) - s2*a*H(m1) - s1*s2*b) / (s2*a*r1 - s1*r2) (mod n)
+func RecoverKeyFromRelatedNonces(message1 []byte, r1, s1 *big.Int, message2 []byte, r2, s2 *big.Int, a, b *big.Int, hash crypto.Hash, publicKey *PublicKey) (d *big.Int, err error) {
+
+	N := publicKey.Curve.N
+	z1, _, err := hashToInt(message1, hash, N)
+	if err != nil {
+		return nil, err
+	}
+	z2, _, err := hashToInt(message2, hash, N)
+	if err != nil {
+		return nil, err
+	}
+	s2a := new(big.Int).Mul(s2, a)
+
+	num := new(big.Int).Mul(s1, z2)
+	num = num.Sub(num, new(big.Int).Mul(s2a, z1))
+	num = num.Sub(num, new(big.Int).Mul(new(big.Int).Mul(s1, s2), b))
+	den := new(big.Int).Mul(s2a, r1)
+	den = den.Sub(den, new(big.Int).Mul(s1, r2))
+	den = den.Mod(den, N)
+	if den.Sign() == 0 {
+		return nil, fmt.Errorf("signatures do not determine the key")
+	}
+	d = num.Mul(num, den.ModInverse(den, N))
+	d = d.Mod(d, N)
+	return checkPrivateKey(d, publicKey)
+}
+
+//RecoverPublicKeys returns every public key for which (r,s) is a valid
+//signature over message, like Ethereum's ecrecover. For each x = r + j*n
+//below p and both points R = (x, +-y) of order n
+//  Q = (s*R - H(m)*G) / r
+//Ethereum picks one of these with the recovery id that is sent along with
+//the signature.
+func RecoverPublicKeys(message []byte, r, s *big.Int, hash crypto.Hash, curve shortWeierstrassCurve) (keys []*PublicKey, err error) {
+
+	N := curve.N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(N) >= 0 || s.Cmp(N) >= 0 {
+		return nil, ErrRangeCheck
+	}
+	z, _, err := hashToInt(message, hash, N)
+	if err != nil {
+		return nil, err
+	}
+	rinv := new(big.Int).ModInverse(r, N)
+	//u1 = -H(m)/r and u2 = s/r so that Q = u1*G + u2*R
+	u1 := new(big.Int).Neg(z)
+	u1 = u1.Mul(u1, rinv)
+	u1 = u1.Mod(u1, N)
+	u2 := new(big.Int).Mul(s, rinv)
+	u2 = u2.Mod(u2, N)
+	gx, gy := curve.ScalarBaseMult(u1.Bytes())
+
+	for x := new(big.Int).Set(r); x.Cmp(curve.P) < 0; x = new(big.Int).Add(x, N) {
+		rhs := new(big.Int).Exp(x, three, curve.P)
+		rhs = rhs.Add(rhs, new(big.Int).Mul(curve.A, x))
+		rhs = rhs.Add(rhs, curve.B)
+		rhs = rhs.Mod(rhs, curve.P)
+		y := new(big.Int).ModSqrt(rhs, curve.P)
+		if y == nil {
+			continue
+		}
+		//on curves with a cofactor R has to be in the subgroup of order n
+		if ox, oy := curve.ScalarMult(x, y, N.Bytes()); !curve.isZeroPoint(ox, oy) {
+			continue
+		}
+		for _, ry := range []*big.Int{y, new(big.Int).Sub(curve.P, y)} {
+			qx, qy := curve.ScalarMult(x, ry, u2.Bytes())
+			qx, qy = curve.Add(gx, gy, qx, qy)
+			if curve.isZeroPoint(qx, qy) {
+				continue
+			}
+			keys = append(keys, &PublicKey{Curve: curve, X: qx, Y: qy})
+			if y.Sign() == 0 {
+				break
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public key matches the signature")
+	}
+	return keys, nil
+}
@@ -0,0 +1,160 @@
+package elliptic
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	_ "crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+//p256 returns the NIST P-256 curve as a shortWeierstrassCurve.
+func p256() shortWeierstrassCurve {
+	params := elliptic.P256().Params()
+	return NewCurve(big.NewInt(-3), params.B, params.P, params.N, params.Gx, params.Gy)
+}
+
+func ecdsaCurves() map[string]shortWeierstrassCurve {
+	return map[string]shortWeierstrassCurve{
+		"cryptopals": curve,
+		"p256":       p256(),
+	}
+}
+
+func TestECDSASignVerify(t *testing.T) {
+
+	for name, c := range ecdsaCurves() {
+		priv, err := GenerateKey(c)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		message := []byte("hello world")
+		r, s, err := ECDSASign(message, crypto.SHA256, priv)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		if err = ECDSAVerify(message, r, s, crypto.SHA256, priv.PublicKey); err != nil {
+			t.Errorf("%s: valid signature did not verify: %v", name, err)
+			return
+		}
+		if err = ECDSAVerify([]byte("hello world!"), r, s, crypto.SHA256, priv.PublicKey); err != ErrMismatch {
+			t.Errorf("%s: expected ErrMismatch for a different message, got %v", name, err)
+			return
+		}
+		if err = ECDSAVerify(message, r, c.N, crypto.SHA256, priv.PublicKey); err != ErrRangeCheck {
+			t.Errorf("%s: expected ErrRangeCheck, got %v", name, err)
+			return
+		}
+		//(r, n-s) is also a valid signature
+		twin := new(big.Int).Sub(c.N, s)
+		if err = ECDSAVerify(message, r, twin, crypto.SHA256, priv.PublicKey); err != nil {
+			t.Errorf("%s: (r, n-s) did not verify: %v", name, err)
+			return
+		}
+	}
+}
+
+func TestECDSAP256Interop(t *testing.T) {
+
+	//a signature made with crypto/ecdsa parameters verifies here
+	c := p256()
+	d, _ := new(big.Int).SetString("c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", 16)
+	x, _ := new(big.Int).SetString("60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6", 16)
+	y, _ := new(big.Int).SetString("7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299", 16)
+	//RFC 6979 A.2.5, SHA-256, message "sample"
+	r, _ := new(big.Int).SetString("efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716", 16)
+	s, _ := new(big.Int).SetString("f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8", 16)
+	gx, gy := c.ScalarBaseMult(d.Bytes())
+	if !c.PointEquals(gx, gy, x, y) {
+		t.Errorf("public key does not match the RFC 6979 test vector")
+		return
+	}
+	pub := &PublicKey{Curve: c, X: x, Y: y}
+	if err := ECDSAVerify([]byte("sample"), r, s, crypto.SHA256, pub); err != nil {
+		t.Errorf("RFC 6979 signature did not verify: %v", err)
+		return
+	}
+}
+
+func TestRecoverKeyFromReusedNonce(t *testing.T) {
+
+	for name, c := range ecdsaCurves() {
+		priv, _ := GenerateKey(c)
+		k, _ := ECDSARandomNonce(nil, priv)
+		fixed := func(digest []byte, privateKey *PrivateKey) (*big.Int, error) {
+			return new(big.Int).Set(k), nil
+		}
+		m1, m2 := []byte("first message"), []byte("second message")
+		r1, s1, _ := ECDSASignWithNonce(m1, crypto.SHA256, priv, fixed)
+		r2, s2, _ := ECDSASignWithNonce(m2, crypto.SHA256, priv, fixed)
+		d, err := RecoverKeyFromReusedNonce(m1, r1, s1, m2, r2, s2, crypto.SHA256, priv.PublicKey)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		if d.Cmp(priv.D) != 0 {
+			t.Errorf("%s: recovered the wrong private key", name)
+			return
+		}
+	}
+}
+
+func TestRecoverKeyFromRelatedNonces(t *testing.T) {
+
+	for name, c := range ecdsaCurves() {
+		priv, _ := GenerateKey(c)
+		a := big.NewInt(1103515245)
+		b := big.NewInt(12345)
+		//nonces from a linear congruential generator
+		k, _ := ECDSARandomNonce(nil, priv)
+		lcg := func(digest []byte, privateKey *PrivateKey) (*big.Int, error) {
+			current := new(big.Int).Set(k)
+			k = k.Mul(k, a)
+			k = k.Add(k, b)
+			k = k.Mod(k, c.N)
+			return current, nil
+		}
+		m1, m2 := []byte("first message"), []byte("second message")
+		r1, s1, _ := ECDSASignWithNonce(m1, crypto.SHA256, priv, lcg)
+		r2, s2, _ := ECDSASignWithNonce(m2, crypto.SHA256, priv, lcg)
+		d, err := RecoverKeyFromRelatedNonces(m1, r1, s1, m2, r2, s2, a, b, crypto.SHA256, priv.PublicKey)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		if d.Cmp(priv.D) != 0 {
+			t.Errorf("%s: recovered the wrong private key", name)
+			return
+		}
+	}
+}
+
+func TestRecoverPublicKeys(t *testing.T) {
+
+	for name, c := range ecdsaCurves() {
+		priv, _ := GenerateKey(c)
+		message := []byte("recover me")
+		r, s, _ := ECDSASign(message, crypto.SHA256, priv)
+		keys, err := RecoverPublicKeys(message, r, s, crypto.SHA256, c)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			return
+		}
+		found := false
+		for _, key := range keys {
+			if err := ECDSAVerify(message, r, s, crypto.SHA256, key); err != nil {
+				t.Errorf("%s: recovered key does not verify the signature", name)
+				return
+			}
+			if c.PointEquals(key.X, key.Y, priv.PublicKey.X, priv.PublicKey.Y) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: the signer's key was not recovered", name)
+			return
+		}
+	}
+}
@@ -20,7 +20,8 @@ type scalarMultOracle func(x, y *big.Int) (*big.Int, *big.Int)
 
 type shortWeierstrassCurve struct {
 	*elliptic.CurveParams
-	A *big.Int
+	A         *big.Int
+	combCache *combCache
 }
 
 //NewCurve creates a new curve that implements the `elliptic.Curve` interface
@@ -40,6 +41,7 @@ func NewCurve(a, b, p, n, gx, gy *big.Int) (curve shortWeierstrassCurve) {
 	curve = shortWeierstrassCurve{
 		CurveParams: &curveParams,
 		A:           a,
+		combCache:   &combCache{},
 	}
 	return curve
 }
@@ -125,20 +127,10 @@ func (curve shortWeierstrassCurve) Double(x1, y1 *big.Int) (x, y *big.Int) {
 	return curve.Add(x1, y1, x1, y1)
 }
 
-//ScalarMult returns k*(x1, y1).
+//ScalarMult returns k*(x1, y1). The multiplication is done in Jacobian
+//coordinates and only the result is converted back to affine coordinates.
 func (curve shortWeierstrassCurve) ScalarMult(x1, y1 *big.Int, k []byte) (x, y *big.Int) {
-	K := new(big.Int).SetBytes(k)
-	Qx := big.NewInt(0)
-	Qy := big.NewInt(1)
-
-	for i := K.BitLen(); i >= 0; i-- {
-		bit := K.Bit(i)
-		Qx, Qy = curve.Double(Qx, Qy)
-		if bit == 1 {
-			Qx, Qy = curve.Add(Qx, Qy, x1, y1)
-		}
-	}
-	return Qx, Qy
+	return curve.ScalarMultWith(x1, y1, k, DoubleAndAdd)
 }
 
 //ScalarBaseMult returns k*(x1, y1) where (x1, y1) is the base point for the
@@ -198,6 +190,15 @@ func (curve shortWeierstrassCurve) randomPoint() (x, y *big.Int) {
 	return
 }
 
+//lift returns a y for which (x, y) is on the curve, or nil if there is none.
+func (curve shortWeierstrassCurve) lift(x *big.Int) *big.Int {
+	rhs := new(big.Int).Exp(x, three, curve.P)
+	rhs = rhs.Add(rhs, new(big.Int).Mul(curve.A, x))
+	rhs = rhs.Add(rhs, curve.B)
+	rhs = rhs.Mod(rhs, curve.P)
+	return new(big.Int).ModSqrt(rhs, curve.P)
+}
+
 func (curve shortWeierstrassCurve) pointWithSpecifiedOrder(r *big.Int) (*big.Int, *big.Int) {
 
 	for {
@@ -227,24 +228,165 @@ func (curve shortWeierstrassCurve) ComputeIndexWithinRange(a, b, x, y, min, max
 
 }
 
+//bsgsIndexUpToSign finds k in [0, n) with k*(gx, gy) = +-(qx, qy) using
+//baby-step giant-step. Only the x-coordinate of the target is needed.
+func (curve shortWeierstrassCurve) bsgsIndexUpToSign(qx, gx, gy, n *big.Int) (k *big.Int, err error) {
+
+	y := curve.lift(qx)
+	if y == nil {
+		return nil, fmt.Errorf("target is not on the curve")
+	}
+	m := bbig.SqrtBig(n)
+	m = m.Add(m, one)
+
+	//baby steps: x(j*G) for j in [0, m)
+	baby := make(map[string]int64)
+	Bx, By := big.NewInt(0), big.NewInt(1)
+	for j := int64(0); j < m.Int64(); j++ {
+		baby[Bx.String()] = j
+		Bx, By = curve.Add(Bx, By, gx, gy)
+	}
+
+	//giant steps: Q - i*m*G. A match on x gives Q = (i*m +- j)*G.
+	mx, my := curve.ScalarMult(gx, gy, m.Bytes())
+	if !curve.isZeroPoint(mx, my) {
+		mx, my = curve.invertPoint(mx, my)
+	}
+	Tx, Ty := qx, y
+	for i := int64(0); i <= m.Int64(); i++ {
+		if j, ok := baby[Tx.String()]; ok {
+			k = new(big.Int).Mul(big.NewInt(i), m)
+			k = k.Add(k, big.NewInt(j))
+			return k.Mod(k, n), nil
+		}
+		Tx, Ty = curve.Add(Tx, Ty, mx, my)
+	}
+	return nil, IndexNotRecoveredErr
+}
+
+//Kangaroo implements Pollard's kangaroo algorithm for solving s*(x,y) = (a,b)
+//with s in [min, max]. Jumps are powers of two picked from the x-coordinate of
+//the current point with a mean of about sqrt(max - min)/2.
+func (curve shortWeierstrassCurve) Kangaroo(a, b, x, y, min, max *big.Int) (index *big.Int, err error) {
+
+	width := new(big.Int).Sub(max, min)
+	mean := bbig.SqrtBig(width)
+	mean = mean.Rsh(mean, 1)
+	k := int64(1)
+	for big.NewInt((int64(1)<<uint(k+1))/(k+1)).Cmp(mean) <= 0 && k < 62 {
+		k++
+	}
+	K := big.NewInt(k)
+
+	//jumps[i] = 2^i*(x,y)
+	jumpX := make([]*big.Int, k)
+	jumpY := make([]*big.Int, k)
+	jumpX[0], jumpY[0] = x, y
+	for i := int64(1); i < k; i++ {
+		jumpX[i], jumpY[i] = curve.Double(jumpX[i-1], jumpY[i-1])
+	}
+	f := func(px *big.Int) int64 {
+		return new(big.Int).Mod(px, K).Int64()
+	}
+
+	//jump adds jumps[j] to (px, py). The kangaroos almost never land on a
+	//jump point or its inverse so the chord formula is used directly and Add
+	//is only needed when the x-coordinates match.
+	m := new(big.Int)
+	t := new(big.Int)
+	jump := func(px, py *big.Int, j int64) (*big.Int, *big.Int) {
+		if curve.isZeroPoint(px, py) || px.Cmp(jumpX[j]) == 0 {
+			return curve.Add(px, py, jumpX[j], jumpY[j])
+		}
+		//m = (y2 - y1) / (x2 - x1)
+		t.Sub(jumpX[j], px)
+		t.ModInverse(t.Mod(t, curve.P), curve.P)
+		m.Sub(jumpY[j], py)
+		m.Mul(m, t)
+		m.Mod(m, curve.P)
+
+		rx := new(big.Int).Mul(m, m)
+		rx.Sub(rx, px)
+		rx.Sub(rx, jumpX[j])
+		rx.Mod(rx, curve.P)
+
+		ry := new(big.Int).Sub(px, rx)
+		ry.Mul(m, ry)
+		ry.Sub(ry, py)
+		ry.Mod(ry, curve.P)
+		return rx, ry
+	}
+
+	//tame kangaroo
+	N := new(big.Int).Lsh(mean, 2)
+	N = N.Add(N, one)
+	xT := big.NewInt(0)
+	yTx, yTy := curve.ScalarMult(x, y, max.Bytes())
+	for i := big.NewInt(0); i.Cmp(N) < 0; i = i.Add(i, one) {
+		j := f(yTx)
+		xT = xT.Add(xT, new(big.Int).Lsh(one, uint(j)))
+		yTx, yTy = jump(yTx, yTy, j)
+	}
+
+	//wild kangaroo
+	xW := big.NewInt(0)
+	yWx, yWy := new(big.Int).Mod(a, curve.P), new(big.Int).Mod(b, curve.P)
+	cond := new(big.Int).Add(width, xT)
+	for xW.Cmp(cond) <= 0 {
+		if yWx.Cmp(yTx) == 0 && yWy.Cmp(yTy) == 0 {
+			index = new(big.Int).Add(max, xT)
+			index = index.Sub(index, xW)
+			return index, nil
+		}
+		j := f(yWx)
+		xW = xW.Add(xW, new(big.Int).Lsh(one, uint(j)))
+		yWx, yWy = jump(yWx, yWy, j)
+	}
+	return nil, IndexNotRecoveredErr
+}
+
 //pohligHellmanOnline implements the invalid curve attack against a specified
 //curve `curve` and an oracle function `oracle` that computes scalarmults on
-//the input point. This method takes pre-generated small-order curves as input
-//because I have not implemented point counting yet.
+//the input point. This method takes pre-generated small-order curves as input.
+//If the order of a curve is not supplied it is computed with Order. If no
+//curves are supplied, curves are generated with weakCurves until their small
+//factors cover the order of curve's base point. Factors of supplied curves
+//are used up to 1048576 but only those below smallFactorBound are used for
+//generated curves.
 func (curve shortWeierstrassCurve) pohligHellmanOnline(smallOrderCurves []shortWeierstrassCurve, oracle scalarMultOracle) (index, newmod *big.Int, err error) {
 
-	for _, soc := range smallOrderCurves {
-		if soc.N != nil && soc.N.Cmp(zero) == 0 {
-			return nil, nil, fmt.Errorf("order of smallOrderCurve not supplied")
+	factorBound := int64(1048576)
+	if len(smallOrderCurves) == 0 {
+		factorBound = smallFactorBound
+		bound := curve.N
+		if bound == nil || bound.Cmp(zero) == 0 {
+			if bound, err = curve.Order(); err != nil {
+				return nil, nil, err
+			}
+		}
+		if smallOrderCurves, err = curve.weakCurves(bound); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	curves := make([]shortWeierstrassCurve, len(smallOrderCurves))
+	for i, soc := range smallOrderCurves {
+		curves[i] = soc
+		if soc.N == nil || soc.N.Cmp(zero) == 0 {
+			n, err := soc.Order()
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to count points on smallOrderCurve: %v", err)
+			}
+			curves[i] = NewCurve(soc.A, soc.B, soc.P, n, soc.Gx, soc.Gy)
 		}
 	}
 
 	indices := make([]*big.Int, 0)
 	moduli := make([]*big.Int, 0)
 
-	for _, soc := range smallOrderCurves {
+	for _, soc := range curves {
 		mmo := new(big.Int).SetBytes(soc.N.Bytes())
-		factors, _ := bbig.Factor(mmo, 1048576)
+		factors, _ := bbig.Factor(mmo, factorBound)
 
 	NewFactor:
 		for factor, _ := range factors {
@@ -26,8 +26,7 @@ func init() {
 	hund, _ = new(big.Int).SetString("100", 10)
 	hundX, _ = new(big.Int).SetString("12246423879899346038895890356990169239", 10)
 	hundY, _ = new(big.Int).SetString("58231960761567435246734586214813749649", 10)
-	//TODO(kkl): using zero for curve order until SEA is implmented.
-	curve = NewCurve(a, b, p, zero, gx, gy)
+	curve = NewCurve(a, b, p, order, gx, gy)
 }
 
 func TestCryptopals59(t *testing.T) {
@@ -59,6 +58,23 @@ func TestCryptopals59(t *testing.T) {
 	if ind.Cmp(priv) != 0 {
 		t.Errorf("failed to recover private key")
 	}
+
+	if !testing.Short() {
+		//let pohligHellmanOnline count the points itself
+		uncounted := []shortWeierstrassCurve{
+			NewCurve(a, b1, p, zero, gx, gy),
+			NewCurve(a, b2, p, zero, gx, gy),
+			NewCurve(a, b3, p, zero, gx, gy),
+		}
+		ind, _, err = curve.pohligHellmanOnline(uncounted, oracle)
+		if err != nil {
+			t.Errorf("%v", err)
+			return
+		}
+		if ind.Cmp(priv) != 0 {
+			t.Errorf("failed to recover private key with computed orders")
+		}
+	}
 }
 
 func TestCurveAddition(t *testing.T) {
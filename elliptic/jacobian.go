@@ -0,0 +1,144 @@
+package elliptic
+
+import (
+	"math/big"
+)
+
+//jacobianPoint is a point (X : Y : Z) in Jacobian coordinates. It represents
+//the affine point (X/Z^2, Y/Z^3), so adding and doubling points does not need
+//a modular inverse. The point at infinity has Infinity set and X, Y and Z are
+//ignored.
+type jacobianPoint struct {
+	X, Y, Z  *big.Int
+	Infinity bool
+}
+
+//infinity returns the point at infinity.
+func infinity() jacobianPoint {
+	return jacobianPoint{Infinity: true}
+}
+
+//toJacobian converts the affine point (x, y) to Jacobian coordinates. The
+//(0, 1) sentinel used for the zero point by the affine methods becomes the
+//point at infinity.
+func (curve shortWeierstrassCurve) toJacobian(x, y *big.Int) jacobianPoint {
+	if curve.isZeroPoint(x, y) {
+		return infinity()
+	}
+	return jacobianPoint{
+		X: new(big.Int).Mod(x, curve.P),
+		Y: new(big.Int).Mod(y, curve.P),
+		Z: big.NewInt(1),
+	}
+}
+
+//toAffine converts P back to affine coordinates. The point at infinity is
+//returned as (0, 1).
+func (curve shortWeierstrassCurve) toAffine(P jacobianPoint) (x, y *big.Int) {
+	if P.Infinity {
+		return big.NewInt(0), big.NewInt(1)
+	}
+	zInv := new(big.Int).ModInverse(P.Z, curve.P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	zInv2 = zInv2.Mod(zInv2, curve.P)
+	x = new(big.Int).Mul(P.X, zInv2)
+	x = x.Mod(x, curve.P)
+	y = new(big.Int).Mul(P.Y, zInv2)
+	y = y.Mul(y, zInv)
+	y = y.Mod(y, curve.P)
+	return
+}
+
+//jacobianDouble returns 2*P. It uses the doubling formula for an arbitrary a
+//("dbl-2007-bl" in the Explicit-Formulas Database). b is never used, so it
+//also works for points that are not on the curve.
+func (curve shortWeierstrassCurve) jacobianDouble(P jacobianPoint) jacobianPoint {
+	if P.Infinity || P.Y.Sign() == 0 {
+		return infinity()
+	}
+	p := curve.P
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+
+	XX := mod(new(big.Int).Mul(P.X, P.X))
+	YY := mod(new(big.Int).Mul(P.Y, P.Y))
+	YYYY := mod(new(big.Int).Mul(YY, YY))
+	ZZ := mod(new(big.Int).Mul(P.Z, P.Z))
+	//S = 2*((X+YY)^2 - XX - YYYY)
+	S := new(big.Int).Add(P.X, YY)
+	S = S.Mul(S, S)
+	S = S.Sub(S, XX)
+	S = S.Sub(S, YYYY)
+	S = mod(S.Lsh(S, 1))
+	//M = 3*XX + a*ZZ^2
+	M := new(big.Int).Mul(ZZ, ZZ)
+	M = M.Mul(M, curve.A)
+	M = M.Add(M, new(big.Int).Mul(XX, three))
+	M = mod(M)
+	//X3 = M^2 - 2*S
+	X3 := new(big.Int).Mul(M, M)
+	X3 = mod(X3.Sub(X3, new(big.Int).Lsh(S, 1)))
+	//Y3 = M*(S - X3) - 8*YYYY
+	Y3 := new(big.Int).Sub(S, X3)
+	Y3 = Y3.Mul(Y3, M)
+	Y3 = mod(Y3.Sub(Y3, new(big.Int).Lsh(YYYY, 3)))
+	//Z3 = (Y+Z)^2 - YY - ZZ
+	Z3 := new(big.Int).Add(P.Y, P.Z)
+	Z3 = Z3.Mul(Z3, Z3)
+	Z3 = Z3.Sub(Z3, YY)
+	Z3 = mod(Z3.Sub(Z3, ZZ))
+	return jacobianPoint{X: X3, Y: Y3, Z: Z3}
+}
+
+//jacobianAdd returns P + Q using the "add-2007-bl" formula. Adding a point to
+//itself falls back to jacobianDouble and adding a point to its inverse gives
+//the point at infinity.
+func (curve shortWeierstrassCurve) jacobianAdd(P, Q jacobianPoint) jacobianPoint {
+	if P.Infinity {
+		return Q
+	}
+	if Q.Infinity {
+		return P
+	}
+	p := curve.P
+	mod := func(v *big.Int) *big.Int { return v.Mod(v, p) }
+
+	Z1Z1 := mod(new(big.Int).Mul(P.Z, P.Z))
+	Z2Z2 := mod(new(big.Int).Mul(Q.Z, Q.Z))
+	U1 := mod(new(big.Int).Mul(P.X, Z2Z2))
+	U2 := mod(new(big.Int).Mul(Q.X, Z1Z1))
+	S1 := new(big.Int).Mul(P.Y, Q.Z)
+	S1 = mod(S1.Mul(S1, Z2Z2))
+	S2 := new(big.Int).Mul(Q.Y, P.Z)
+	S2 = mod(S2.Mul(S2, Z1Z1))
+
+	H := mod(new(big.Int).Sub(U2, U1))
+	if H.Sign() == 0 {
+		if S1.Cmp(S2) == 0 {
+			return curve.jacobianDouble(P)
+		}
+		return infinity()
+	}
+	//I = (2*H)^2, J = H*I, r = 2*(S2 - S1), V = U1*I
+	I := new(big.Int).Lsh(H, 1)
+	I = mod(I.Mul(I, I))
+	J := mod(new(big.Int).Mul(H, I))
+	r := new(big.Int).Sub(S2, S1)
+	r = mod(r.Lsh(r, 1))
+	V := mod(new(big.Int).Mul(U1, I))
+	//X3 = r^2 - J - 2*V
+	X3 := new(big.Int).Mul(r, r)
+	X3 = X3.Sub(X3, J)
+	X3 = mod(X3.Sub(X3, new(big.Int).Lsh(V, 1)))
+	//Y3 = r*(V - X3) - 2*S1*J
+	Y3 := new(big.Int).Sub(V, X3)
+	Y3 = Y3.Mul(Y3, r)
+	S1J := new(big.Int).Mul(S1, J)
+	Y3 = mod(Y3.Sub(Y3, S1J.Lsh(S1J, 1)))
+	//Z3 = ((Z1+Z2)^2 - Z1Z1 - Z2Z2)*H
+	Z3 := new(big.Int).Add(P.Z, Q.Z)
+	Z3 = Z3.Mul(Z3, Z3)
+	Z3 = Z3.Sub(Z3, Z1Z1)
+	Z3 = Z3.Sub(Z3, Z2Z2)
+	Z3 = mod(Z3.Mul(Z3, H))
+	return jacobianPoint{X: X3, Y: Y3, Z: Z3}
+}
@@ -0,0 +1,103 @@
+package elliptic
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestJacobianMatchesAffine(t *testing.T) {
+
+	for i := 0; i < 20; i++ {
+		x1, y1 := curve.randomPoint()
+		x2, y2 := curve.randomPoint()
+		P := curve.toJacobian(x1, y1)
+		Q := curve.toJacobian(x2, y2)
+		//scale Q so that Z is not one
+		z, _ := rand.Int(rand.Reader, curve.P)
+		z = z.Add(z, one)
+		zz := new(big.Int).Mul(z, z)
+		Q.X = Q.X.Mul(Q.X, zz)
+		Q.X = Q.X.Mod(Q.X, curve.P)
+		Q.Y = Q.Y.Mul(Q.Y, zz.Mul(zz, z))
+		Q.Y = Q.Y.Mod(Q.Y, curve.P)
+		Q.Z = z.Mod(z, curve.P)
+
+		ax, ay := curve.Add(x1, y1, x2, y2)
+		jx, jy := curve.toAffine(curve.jacobianAdd(P, Q))
+		if !curve.PointEquals(ax, ay, jx, jy) {
+			t.Errorf("test %d: Jacobian addition does not match affine addition", i)
+			return
+		}
+		ax, ay = curve.Double(x2, y2)
+		jx, jy = curve.toAffine(curve.jacobianDouble(Q))
+		if !curve.PointEquals(ax, ay, jx, jy) {
+			t.Errorf("test %d: Jacobian doubling does not match affine doubling", i)
+			return
+		}
+		jx, jy = curve.toAffine(curve.jacobianAdd(P, P))
+		ax, ay = curve.Double(x1, y1)
+		if !curve.PointEquals(ax, ay, jx, jy) {
+			t.Errorf("test %d: adding a point to itself does not double it", i)
+			return
+		}
+	}
+}
+
+func TestJacobianInfinity(t *testing.T) {
+
+	x, y := curve.randomPoint()
+	P := curve.toJacobian(x, y)
+	nx, ny := curve.invertPoint(x, y)
+	if !curve.jacobianAdd(P, curve.toJacobian(nx, ny)).Infinity {
+		t.Errorf("adding a point to its inverse did not give infinity")
+		return
+	}
+	sum := curve.jacobianAdd(P, infinity())
+	sx, sy := curve.toAffine(sum)
+	if !curve.PointEquals(sx, sy, x, y) {
+		t.Errorf("adding infinity changed the point")
+		return
+	}
+	if !curve.toJacobian(zero, one).Infinity {
+		t.Errorf("the zero point did not convert to infinity")
+		return
+	}
+	zx, zy := curve.toAffine(infinity())
+	if !curve.isZeroPoint(zx, zy) {
+		t.Errorf("infinity did not convert to the zero point")
+		return
+	}
+
+	//(x0, 0) has order 2 on y^2 = x^3 - x
+	small := NewCurve(big.NewInt(-1), zero, big.NewInt(10007), zero, zero, zero)
+	if !small.jacobianDouble(small.toJacobian(one, zero)).Infinity {
+		t.Errorf("doubling a point of order 2 did not give infinity")
+		return
+	}
+}
+
+func TestScalarMultJacobian(t *testing.T) {
+
+	//k*G computed with repeated affine addition
+	x, y := big.NewInt(0), big.NewInt(1)
+	for k := int64(0); k < 50; k++ {
+		sx, sy := curve.ScalarMult(gx, gy, big.NewInt(k).Bytes())
+		if !curve.PointEquals(sx, sy, x, y) {
+			t.Errorf("%d*G does not match repeated addition", k)
+			return
+		}
+		x, y = curve.Add(x, y, gx, gy)
+	}
+
+	ox, oy := curve.ScalarMult(gx, gy, order.Bytes())
+	if !curve.isZeroPoint(ox, oy) {
+		t.Errorf("scalarmult by the order of the base point was not zero")
+		return
+	}
+	hx, hy := curve.ScalarMult(gx, gy, hund.Bytes())
+	if !curve.PointEquals(hx, hy, hundX, hundY) {
+		t.Errorf("scalarmult by 100 returned the wrong point")
+		return
+	}
+}
@@ -0,0 +1,44 @@
+package elliptic
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+//PublicKey is a point (X, Y) on Curve. It is used by both ECDSA and ECDH.
+type PublicKey struct {
+	Curve shortWeierstrassCurve
+	X, Y  *big.Int
+}
+
+//PrivateKey is a scalar D together with the public key D*G. It is used by
+//both ECDSA and ECDH.
+type PrivateKey struct {
+	PublicKey *PublicKey
+	D         *big.Int
+}
+
+//GenerateKey generates a keypair on curve. The private key d is in [1, n)
+//where n is the order of the base point.
+func GenerateKey(curve shortWeierstrassCurve) (priv *PrivateKey, err error) {
+	if curve.N == nil || curve.N.Sign() == 0 {
+		return nil, fmt.Errorf("order of the base point not supplied")
+	}
+	var d *big.Int
+	for {
+		d, err = rand.Int(rand.Reader, curve.N)
+		if err != nil {
+			return nil, err
+		}
+		if d.Sign() != 0 {
+			break
+		}
+	}
+	x, y := curve.ScalarBaseMult(d.Bytes())
+	priv = &PrivateKey{
+		PublicKey: &PublicKey{Curve: curve, X: x, Y: y},
+		D:         d,
+	}
+	return priv, nil
+}
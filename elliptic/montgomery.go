@@ -11,6 +11,9 @@ import (
 
 //montgomeryCurve represents a montgomery curve with the following formula:
 //B*y^2=x^3+A*x^2+x
+//Unlike for a shortWeierstrassCurve, N is the order of the whole curve, the
+//order of the base point times the cofactor, since the twist attack needs it
+//to get the order of the twist.
 type montgomeryCurve struct {
 	*elliptic.CurveParams
 	A *big.Int
@@ -117,22 +120,43 @@ func (curve montgomeryCurve) ScalarMult(x1 *big.Int, k []byte) (w *big.Int) {
 	return w2
 }
 
-func (curve montgomeryCurve) randomPoint() (x *big.Int) {
-	buf := make([]byte, len(curve.P.Bytes()))
-	rand.Read(buf)
-	x = new(big.Int).SetBytes(buf)
-	x = x.Mod(x, curve.P)
+//rhs returns u^3 + A*u^2 + u.
+func (curve montgomeryCurve) rhs(u *big.Int) *big.Int {
+	u3 := new(big.Int).Exp(u, three, curve.P)
+
+	au2 := new(big.Int).Exp(u, two, curve.P)
+	au2 = au2.Mul(curve.A, au2)
+	au2 = au2.Mod(au2, curve.P)
 
-	x3 := new(big.Int).Exp(x, three, curve.P)
+	rhs := new(big.Int).Add(u3, au2)
+	rhs = rhs.Add(rhs, u)
+	return rhs.Mod(rhs, curve.P)
+}
+
+//onTwist returns true if u is the u-coordinate of a point on the quadratic
+//twist rather than on the curve, i.e. if (u^3 + A*u^2 + u)/B is not a square.
+func (curve montgomeryCurve) onTwist(u *big.Int) bool {
+	r := new(big.Int).Mul(curve.rhs(u), curve.B)
+	return big.Jacobi(r.Mod(r, curve.P), curve.P) == -1
+}
 
-	ax2 := new(big.Int).Exp(x, two, curve.P)
-	ax2 = ax2.Mul(curve.A, ax2)
-	ax2 = ax2.Mod(ax2, curve.P)
+//randomU returns a random u-coordinate, on the twist if twist is true and on
+//the curve otherwise.
+func (curve montgomeryCurve) randomU(twist bool) (u *big.Int) {
+	buf := make([]byte, len(curve.P.Bytes()))
+	for {
+		rand.Read(buf)
+		u = new(big.Int).SetBytes(buf)
+		u = u.Mod(u, curve.P)
+		if curve.onTwist(u) == twist {
+			return u
+		}
+	}
+}
 
-	rhs := new(big.Int).Add(x3, ax2)
-	rhs = rhs.Add(rhs, x)
-	rhs = rhs.Mod(rhs, curve.P)
-	return rhs
+//randomPoint returns the u-coordinate of a random point on the curve.
+func (curve montgomeryCurve) randomPoint() (x *big.Int) {
+	return curve.randomU(false)
 }
 
 func (curve montgomeryCurve) isZeroPoint(x *big.Int) bool {
@@ -141,19 +165,24 @@ func (curve montgomeryCurve) isZeroPoint(x *big.Int) bool {
 	return curve.PointEquals(xt, zero)
 }
 
-func (curve montgomeryCurve) twistPointWithSpecifiedOrder(twistOrder, primeFactor *big.Int) *big.Int {
+//twistPointWithSpecifiedOrder returns the u-coordinate of a point on the twist
+//whose order is the product of primes. twistOrder is the order of the twist.
+func (curve montgomeryCurve) twistPointWithSpecifiedOrder(twistOrder *big.Int, primes ...*big.Int) *big.Int {
+	order := big.NewInt(1)
+	for _, prime := range primes {
+		order = order.Mul(order, prime)
+	}
+	nr := new(big.Int).Div(twistOrder, order)
+NextPoint:
 	for {
-		x := curve.randomPoint()
-		xsqrt := new(big.Int).ModSqrt(x, curve.P)
-		if xsqrt != nil {
-			continue
-		}
-		fmt.Printf("%d is not square mod %d\n", x, curve.P)
-		nr := new(big.Int).Div(twistOrder, primeFactor)
-		a := curve.ScalarMult(x, nr.Bytes())
-		if !curve.isZeroPoint(a) {
-			return a
+		a := curve.ScalarMult(curve.randomU(true), nr.Bytes())
+		for _, prime := range primes {
+			cofactor := new(big.Int).Div(order, prime)
+			if curve.isZeroPoint(curve.ScalarMult(a, cofactor.Bytes())) {
+				continue NextPoint
+			}
 		}
+		return a
 	}
 }
 
@@ -181,47 +210,197 @@ func (curve montgomeryCurve) ComputeIndexWithinRange(a, x, min, max *big.Int) (i
 
 }
 
-func (curve montgomeryCurve) PohligHellmanOnline(oracle func(*big.Int) *big.Int) (index, newmod *big.Int, err error) {
+//weierstrassForm returns the short Weierstrass curve isomorphic to
+//scale*B*v^2 = u^3 + A*u^2 + u along with the map from u-coordinates to
+//x-coordinates:
+//  x = u/(scale*B) + A/(3*scale*B)
+//  a = (3 - A^2)/(3*(scale*B)^2)
+//  b = (2*A^3 - 9*A)/(27*(scale*B)^3)
+//A scale of one gives the curve itself and a non-residue gives its twist.
+//Unlike the u-coordinate ladder, the Weierstrass form can add points.
+func (curve montgomeryCurve) weierstrassForm(scale *big.Int) (shortWeierstrassCurve, func(u *big.Int) *big.Int) {
+	P := curve.P
+	B := new(big.Int).Mul(scale, curve.B)
+	B = B.Mod(B, P)
+	inv := func(v *big.Int) *big.Int {
+		return new(big.Int).ModInverse(new(big.Int).Mod(v, P), P)
+	}
+	A := curve.A
+	AA := new(big.Int).Mul(A, A)
+
+	a := new(big.Int).Sub(three, AA)
+	a = a.Mul(a, inv(new(big.Int).Mul(three, new(big.Int).Mul(B, B))))
+	a = a.Mod(a, P)
+
+	b := new(big.Int).Mul(two, new(big.Int).Mul(AA, A))
+	b = b.Sub(b, new(big.Int).Mul(big.NewInt(9), A))
+	B3 := new(big.Int).Exp(B, three, P)
+	b = b.Mul(b, inv(new(big.Int).Mul(big.NewInt(27), B3)))
+	b = b.Mod(b, P)
+
+	Binv := inv(B)
+	shift := new(big.Int).Mul(A, inv(new(big.Int).Mul(three, B)))
+	toX := func(u *big.Int) *big.Int {
+		x := new(big.Int).Mul(u, Binv)
+		x = x.Add(x, shift)
+		return x.Mod(x, P)
+	}
+	return NewCurve(a, b, P, zero, zero, zero), toX
+}
 
-	indices := make([]*big.Int, 0)
-	moduli := make([]*big.Int, 0)
+//twistFactorBound is the largest prime factor of the twist order used by
+//PohligHellmanOnline.
+const twistFactorBound = 1 << 22
+
+//kangarooAttempts is the number of walks RecoverKeyFromTwistResidues tries for
+//each choice of signs before moving on.
+const kangarooAttempts = 4
+
+//PohligHellmanOnline implements the twist attack against an oracle that
+//computes u-coordinate scalar multiplications with a fixed private key k and
+//does not check that its input is on the curve. Small order points on the
+//quadratic twist reveal k modulo each small prime factor of the twist order,
+//which is 2p + 2 - N where N is the order of the whole curve.
+//
+//The ladder only sees u-coordinates, so each residue is only known up to sign.
+//The signs are resolved one factor at a time with an extra query using a
+//point whose order is the product of the factors so far, so the result is
+//  k = +-index (mod newmod)
+//progress, if not nil, is called with every prime factor and the residue
+//recovered for it.
+func (curve montgomeryCurve) PohligHellmanOnline(oracle func(*big.Int) *big.Int, progress func(factor, residue *big.Int)) (index, newmod *big.Int, err error) {
+
+	if curve.N == nil || curve.N.Sign() == 0 {
+		return nil, nil, fmt.Errorf("order of the curve not supplied")
+	}
+	twistOrder := new(big.Int).Lsh(new(big.Int).Add(curve.P, one), 1)
+	twistOrder = twistOrder.Sub(twistOrder, curve.N)
+
+	//the twist in Weierstrass form, where indices can be found with
+	//baby-step giant-step
+	d := big.NewInt(2)
+	for big.Jacobi(d, curve.P) != -1 {
+		d = d.Add(d, one)
+	}
+	twist, toX := curve.weierstrassForm(d)
 
-	//Count points on the twist using knowledge of the order of the curve.
-	curveOrder := curve.N
-	fmt.Printf("curve order %d\n", curveOrder)
-	totalPoints := new(big.Int).Mul(big.NewInt(2), curve.P)
-	totalPoints = totalPoints.Add(totalPoints, big.NewInt(2))
-	fmt.Printf("total points %d\n", totalPoints)
-	twistOrder := new(big.Int).Sub(totalPoints, curveOrder)
-	fmt.Printf("twist order %d\n", twistOrder)
+	factors, _ := bbig.Factor(twistOrder, twistFactorBound)
+	var primes []*big.Int
+	for factor := range factors {
+		if factor != 2 {
+			primes = append(primes, big.NewInt(factor))
+		}
+	}
+	if len(primes) == 0 {
+		return nil, nil, fmt.Errorf("twist order has no small odd factors")
+	}
 
-	factors, _ := bbig.Factor(twistOrder, 65536)
+	for _, prime := range primes {
+		u := curve.twistPointWithSpecifiedOrder(twistOrder, prime)
+		x := toX(u)
+		y := twist.lift(x)
+		q := oracle(u)
+		residue := big.NewInt(0)
+		if !curve.isZeroPoint(q) {
+			if residue, err = twist.bsgsIndexUpToSign(toX(q), x, y, prime); err != nil {
+				return nil, nil, err
+			}
+		}
+		if progress != nil {
+			progress(prime, residue)
+		}
+		if newmod == nil {
+			index, newmod = residue, prime
+			continue
+		}
 
-	for factor, _ := range factors {
-		primeFactor := big.NewInt(int64(factor))
-		if primeFactor.Cmp(two) == 0 {
+		//k = +-index (mod newmod) and k = +-residue (mod prime). Up to an
+		//overall sign there are two ways to combine them.
+		c1, M, _ := bbig.CRT([]*big.Int{index, residue}, []*big.Int{newmod, prime})
+		c2, _, _ := bbig.CRT([]*big.Int{index, new(big.Int).Sub(prime, residue)}, []*big.Int{newmod, prime})
+		index, newmod = c1, M
+		if c1.Cmp(c2) == 0 {
 			continue
 		}
+		factorsSoFar := make([]*big.Int, 0)
+		for _, p := range primes {
+			if new(big.Int).Mod(M, p).Sign() == 0 {
+				factorsSoFar = append(factorsSoFar, p)
+			}
+		}
+		w := curve.twistPointWithSpecifiedOrder(twistOrder, factorsSoFar...)
+		if !curve.PointEquals(curve.ScalarMult(w, c1.Bytes()), oracle(w)) {
+			index = c2
+		}
+	}
+	return index, newmod, nil
+}
 
-		ind := new(big.Int)
-		//using an outer loop because twistPointWithSpecifiedOrder sometimes returns non-twist points and i'm not sure why.
-		for {
-			fmt.Printf("new factor %d\n", primeFactor)
-			x := mcurve.twistPointWithSpecifiedOrder(twistOrder, primeFactor)
-			y := oracle(x)
+//RecoverKeyFromTwistResidues finishes the twist attack. Given
+//k = +-index (mod modulus) from PohligHellmanOnline, the public key u = k*G and
+//an upper bound max on k, the rest of k is found with Pollard's kangaroo on
+//the Weierstrass form of the curve. Since u only fixes k*G up to sign, it is
+//enough to find n in [-max/modulus, max/modulus] with
+//  Y - c*G = n*(modulus*G)
+//for c = +-index, where Y is either point with u-coordinate u. Then
+//k = |c + n*modulus|.
+func (curve montgomeryCurve) RecoverKeyFromTwistResidues(index, modulus, u, max *big.Int) (k *big.Int, err error) {
+
+	matches := func(k *big.Int) bool {
+		return k.Sign() > 0 && k.Cmp(max) <= 0 && curve.PointEquals(curve.ScalarMult(curve.Gx, k.Bytes()), u)
+	}
 
-			ind, err = mcurve.ComputeIndexWithinRange(y, x, zero, primeFactor)
+	//the bound is already covered by the residues
+	if modulus.Cmp(max) > 0 {
+		for _, c := range []*big.Int{index, new(big.Int).Sub(modulus, index)} {
+			if matches(c) {
+				return c, nil
+			}
+		}
+		return nil, IndexNotRecoveredErr
+	}
+
+	E, toX := curve.weierstrassForm(one)
+	gx := toX(curve.Gx)
+	gy := E.lift(gx)
+	yx := toX(u)
+	yy := E.lift(yx)
+	if gy == nil || yy == nil {
+		return nil, fmt.Errorf("point is not on the curve")
+	}
+	hx, hy := E.ScalarMult(gx, gy, modulus.Bytes())
+	nMax := new(big.Int).Div(max, modulus)
+	nMax = nMax.Add(nMax, one)
+
+	//the kangaroo can miss, so later attempts shift the range by a random
+	//multiple of (hx, hy) for a different walk
+	for i := 0; i < kangarooAttempts; i++ {
+		shift := big.NewInt(0)
+		if i > 0 {
+			shift, _ = rand.Int(rand.Reader, nMax)
+		}
+		shx, shy := E.ScalarMult(hx, hy, shift.Bytes())
+		lo := new(big.Int).Sub(shift, nMax)
+		hi := new(big.Int).Add(shift, nMax)
+		for _, c := range []*big.Int{index, new(big.Int).Sub(modulus, index)} {
+			cx, cy := E.ScalarMult(gx, gy, c.Bytes())
+			if !E.isZeroPoint(cx, cy) {
+				cx, cy = E.invertPoint(cx, cy)
+			}
+			tx, ty := E.Add(yx, yy, cx, cy)
+			tx, ty = E.Add(tx, ty, shx, shy)
+			n, err := E.Kangaroo(tx, ty, hx, hy, lo, hi)
 			if err != nil {
-				fmt.Printf("failed to recover index...\n")
 				continue
 			}
-			break
+			k = new(big.Int).Sub(n, shift)
+			k = k.Mul(k, modulus)
+			k = k.Add(k, c)
+			k = k.Abs(k)
+			if matches(k) {
+				return k, nil
+			}
 		}
-		fmt.Printf("recovered index. new residue x mod %d = %d\n", primeFactor, ind)
-		indices = append(indices, ind)
-		moduli = append(moduli, primeFactor)
 	}
-
-	return bbig.CRT(indices, moduli)
-
+	return nil, IndexNotRecoveredErr
 }
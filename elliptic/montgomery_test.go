@@ -1,7 +1,7 @@
 package elliptic
 
 import (
-	"fmt"
+	"crypto/rand"
 	"math/big"
 	"testing"
 )
@@ -36,15 +36,128 @@ func TestLadder(t *testing.T) {
 
 func TestCryptopals60(t *testing.T) {
 
+	//order of the base point
+	q, _ := new(big.Int).SetString("29246302889428143187362802287225875743", 10)
+
 	priv := big.NewInt(705485)
+	if !testing.Short() {
+		priv, _ = rand.Int(rand.Reader, q)
+	}
 	oracle := func(x *big.Int) *big.Int {
-		fmt.Printf("oracle: %d*%d = ", priv, x)
-		a := mcurve.ScalarMult(x, priv.Bytes())
-		fmt.Printf("%d\n", a)
-		return a
-	}
-	ind, _, _ := mcurve.PohligHellmanOnline(oracle)
-	if ind.Cmp(priv) != 0 {
-		t.Errorf("failed to recover private key")
+		return mcurve.ScalarMult(x, priv.Bytes())
+	}
+	pub := mcurve.ScalarMult(mgx, priv.Bytes())
+
+	var factors []*big.Int
+	progress := func(factor, residue *big.Int) {
+		factors = append(factors, factor)
+	}
+	ind, mod, err := mcurve.PohligHellmanOnline(oracle, progress)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if len(factors) == 0 {
+		t.Errorf("progress was not reported")
+		return
+	}
+	neg := new(big.Int).Sub(mod, ind)
+	km := new(big.Int).Mod(priv, mod)
+	if km.Cmp(ind) != 0 && km.Cmp(neg) != 0 {
+		t.Errorf("private key was not recovered up to sign: %d != +-%d (mod %d)", km, ind, mod)
+		return
+	}
+
+	max := big.NewInt(1 << 20)
+	if !testing.Short() {
+		max = q
+	}
+	k, err := mcurve.RecoverKeyFromTwistResidues(ind, mod, pub, max)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if k.Cmp(priv) != 0 {
+		t.Errorf("failed to recover private key: %d != %d", k, priv)
+	}
+}
+
+func TestRecoverKeyFromTwistResidues(t *testing.T) {
+
+	//residues as PohligHellmanOnline returns them, for keys that are just
+	//above the modulus and well above it so the kangaroo has to run
+	mod, _ := rand.Int(rand.Reader, new(big.Int).Lsh(one, 64))
+	mod = mod.Add(mod, new(big.Int).Lsh(one, 64))
+	tests := []struct {
+		multiple int64
+		max      *big.Int
+	}{
+		{1, new(big.Int).Lsh(mod, 1)},
+		{700000, new(big.Int).Lsh(mod, 20)},
+	}
+	for _, test := range tests {
+		r, _ := rand.Int(rand.Reader, mod)
+		priv := new(big.Int).Mul(mod, big.NewInt(test.multiple))
+		priv = priv.Add(priv, r)
+		pub := mcurve.ScalarMult(mgx, priv.Bytes())
+		for _, ind := range []*big.Int{r, new(big.Int).Sub(mod, r)} {
+			k, err := mcurve.RecoverKeyFromTwistResidues(ind, mod, pub, test.max)
+			if err != nil {
+				t.Errorf("%v", err)
+				return
+			}
+			if k.Cmp(priv) != 0 {
+				t.Errorf("failed to recover private key: %d != %d", k, priv)
+				return
+			}
+		}
+	}
+}
+
+func TestWeierstrassForm(t *testing.T) {
+
+	//the curve from challenge 59 is the weierstrass form of the curve from
+	//challenge 60
+	E, toX := mcurve.weierstrassForm(one)
+	if E.A.Cmp(new(big.Int).Mod(a, p)) != 0 || E.B.Cmp(b) != 0 {
+		t.Errorf("unexpected weierstrass curve: a = %d, b = %d", E.A, E.B)
+		return
+	}
+	if toX(mgx).Cmp(gx) != 0 {
+		t.Errorf("base point was not mapped to the challenge 59 base point")
+		return
+	}
+
+	//twist points map to points on the twist's weierstrass form
+	d := big.NewInt(2)
+	for big.Jacobi(d, mp) != -1 {
+		d = d.Add(d, one)
+	}
+	T, toXT := mcurve.weierstrassForm(d)
+	for i := 0; i < 10; i++ {
+		u := mcurve.randomU(true)
+		if !mcurve.onTwist(u) {
+			t.Errorf("randomU returned a point off the twist")
+			return
+		}
+		x := toXT(u)
+		if T.lift(x) == nil {
+			t.Errorf("twist point did not map to the twist")
+			return
+		}
+	}
+}
+
+func TestKangaroo(t *testing.T) {
+
+	k := big.NewInt(3500123)
+	x, y := curve.ScalarBaseMult(k.Bytes())
+	index, err := curve.Kangaroo(x, y, gx, gy, big.NewInt(3000000), big.NewInt(4000000))
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	if index.Cmp(k) != 0 {
+		t.Errorf("incorrect index returned: %d != %d", index, k)
 	}
 }
@@ -0,0 +1,281 @@
+package elliptic
+
+import (
+	"fmt"
+	"math/big"
+
+	bbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+//schoofThreshold is the bit length of p above which Order uses Schoof's
+//algorithm before falling back to baby-step giant-step.
+const schoofThreshold = 40
+
+//bsgsWidthBits bounds the number of candidate orders, as a power of two, left
+//for baby-step giant-step once Schoof's algorithm has narrowed them down.
+const bsgsWidthBits = 36
+
+//orderAttempts is the number of random points used to single out the group
+//order among the candidates.
+const orderAttempts = 20
+
+//Order returns the number of points on the curve, including the point at
+//infinity. For small p the order is found with baby-step giant-step in the
+//Hasse interval p+1-2*sqrt(p) <= #E <= p+1+2*sqrt(p) (Mestre's algorithm,
+//using the quadratic twist when a single curve is ambiguous). For larger p
+//the Schoof-Elkies-Atkin algorithm first computes the trace of Frobenius
+//modulo enough small primes that only a handful of candidates are left for
+//baby-step giant-step. A 256-bit curve like P-256 takes about twenty seconds.
+//Curves with j-invariant 0 or 1728 are counted with complex multiplication
+//instead.
+func (curve shortWeierstrassCurve) Order() (order *big.Int, err error) {
+
+	P := curve.P
+	if P.Cmp(big.NewInt(3)) <= 0 || !P.ProbablyPrime(20) {
+		return nil, fmt.Errorf("p must be a prime larger than 3")
+	}
+	//4a^3 + 27b^2 != 0
+	disc := new(big.Int).Exp(curve.A, three, P)
+	disc = disc.Mul(disc, big.NewInt(4))
+	disc = disc.Add(disc, new(big.Int).Mul(big.NewInt(27), new(big.Int).Mul(curve.B, curve.B)))
+	if disc.Mod(disc, P).Sign() == 0 {
+		return nil, fmt.Errorf("curve is singular")
+	}
+
+	//t = p + 1 - #E is known mod M, so #E = c (mod M).
+	c, M := big.NewInt(0), big.NewInt(1)
+	if P.BitLen() > schoofThreshold {
+		a := new(big.Int).Mod(curve.A, P)
+		b := new(big.Int).Mod(curve.B, P)
+		if a.Sign() == 0 || b.Sign() == 0 {
+			return curve.cmOrder()
+		}
+		width := new(big.Int).Lsh(bbig.SqrtBig(P), 2)
+		target := new(big.Int).Rsh(width, bsgsWidthBits)
+		t, modulus, err := curve.seaTrace(target)
+		if err != nil {
+			return nil, err
+		}
+		c = c.Add(P, one)
+		c = c.Sub(c, t)
+		c = c.Mod(c, modulus)
+		M = modulus
+	}
+
+	order, err = curve.orderWithCongruence(c, M)
+	if err == nil {
+		return order, nil
+	}
+
+	//The twist has trace -t, so its order is 2p + 2 - #E.
+	twist := curve.quadraticTwist()
+	tc := new(big.Int).Lsh(new(big.Int).Add(P, one), 1)
+	tc = tc.Sub(tc, c)
+	tc = tc.Mod(tc, M)
+	twistOrder, terr := twist.orderWithCongruence(tc, M)
+	if terr != nil {
+		return nil, err
+	}
+	order = new(big.Int).Lsh(new(big.Int).Add(P, one), 1)
+	return order.Sub(order, twistOrder), nil
+}
+
+//quadraticTwist returns the curve y^2 = x^3 + a*d^2*x + b*d^3 for a
+//non-residue d mod p.
+func (curve shortWeierstrassCurve) quadraticTwist() shortWeierstrassCurve {
+	P := curve.P
+	d := big.NewInt(2)
+	for big.Jacobi(d, P) != -1 {
+		d = d.Add(d, one)
+	}
+	dd := new(big.Int).Mul(d, d)
+	a := new(big.Int).Mul(curve.A, dd)
+	a = a.Mod(a, P)
+	b := new(big.Int).Mul(curve.B, dd.Mul(dd, d))
+	b = b.Mod(b, P)
+	return NewCurve(a, b, P, zero, zero, zero)
+}
+
+//orderWithCongruence finds the unique N in the Hasse interval with N = c
+//(mod M) and N*Q = 0 for random points Q. An error is returned if random
+//points do not single out one candidate.
+func (curve shortWeierstrassCurve) orderWithCongruence(c, M *big.Int) (order *big.Int, err error) {
+
+	P := curve.P
+	s := bbig.SqrtBig(P)
+	s = s.Add(s, one)
+	lo := new(big.Int).Add(P, one)
+	lo = lo.Sub(lo, new(big.Int).Lsh(s, 1))
+	if lo.Sign() < 0 {
+		lo = big.NewInt(0)
+	}
+	hi := new(big.Int).Add(P, one)
+	hi = hi.Add(hi, new(big.Int).Lsh(s, 1))
+
+	//n0 is the smallest candidate no smaller than lo
+	n0 := new(big.Int).Sub(c, lo)
+	n0 = n0.Mod(n0, M)
+	n0 = n0.Add(n0, lo)
+	if n0.Cmp(hi) > 0 {
+		return nil, fmt.Errorf("no candidate orders")
+	}
+	J := new(big.Int).Sub(hi, n0)
+	J = J.Div(J, M)
+	if J.BitLen() > 62 {
+		return nil, fmt.Errorf("too many candidate orders")
+	}
+
+	var candidates map[int64]bool
+	for attempt := 0; attempt < orderAttempts; attempt++ {
+		x, y := curve.randomPoint()
+		found := curve.bsgsMultiples(x, y, n0, M, J.Int64())
+		if candidates == nil {
+			candidates = found
+		} else {
+			for j := range candidates {
+				if !found[j] {
+					delete(candidates, j)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no candidate order annihilates the curve")
+		}
+		if len(candidates) == 1 {
+			for j := range candidates {
+				order = new(big.Int).Mul(big.NewInt(j), M)
+				return order.Add(order, n0), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("curve order is ambiguous")
+}
+
+//bsgsMultiples returns every j in [0, J] for which (n0 + j*M)*(x, y) = 0
+//using baby-step giant-step.
+func (curve shortWeierstrassCurve) bsgsMultiples(x, y, n0, M *big.Int, J int64) map[int64]bool {
+
+	Rx, Ry := curve.ScalarMult(x, y, n0.Bytes())
+	Sx, Sy := curve.ScalarMult(x, y, M.Bytes())
+
+	m := bbig.SqrtBig(big.NewInt(J+1)).Int64() + 1
+	//baby steps: i*S for i in [0, m)
+	baby := make(map[string][]int64)
+	Bx, By := big.NewInt(0), big.NewInt(1)
+	for i := int64(0); i < m; i++ {
+		key := Bx.String()
+		baby[key] = append(baby[key], i)
+		Bx, By = curve.Add(Bx, By, Sx, Sy)
+	}
+
+	//giant steps: T = -R - g*m*S. A match T = +-i*S gives R + (g*m -+ i)*S = 0.
+	neg := func(x, y *big.Int) (*big.Int, *big.Int) {
+		if curve.isZeroPoint(x, y) {
+			return big.NewInt(0), big.NewInt(1)
+		}
+		return curve.invertPoint(x, y)
+	}
+	mSx, mSy := neg(curve.ScalarMult(Sx, Sy, big.NewInt(m).Bytes()))
+	Tx, Ty := neg(Rx, Ry)
+	found := make(map[int64]bool)
+	for g := int64(0); g*m <= J+m; g++ {
+		for _, i := range baby[Tx.String()] {
+			Ix, Iy := curve.ScalarMult(Sx, Sy, big.NewInt(i).Bytes())
+			if curve.PointEquals(Tx, Ty, Ix, Iy) {
+				if j := g*m + i; j >= 0 && j <= J {
+					found[j] = true
+				}
+			}
+			Ix, Iy = neg(Ix, Iy)
+			if curve.PointEquals(Tx, Ty, Ix, Iy) {
+				if j := g*m - i; j >= 0 && j <= J {
+					found[j] = true
+				}
+			}
+		}
+		Tx, Ty = curve.Add(Tx, Ty, mSx, mSy)
+	}
+	return found
+}
+
+//cornacchia returns x and y with x^2 + d*y^2 = p for a prime p, or nil if
+//there are none. It runs Euclid's algorithm on p and a square root of -d mod p
+//until the remainder drops below sqrt(p).
+func cornacchia(d int64, p *big.Int) (x, y *big.Int) {
+	r := new(big.Int).ModSqrt(new(big.Int).Mod(big.NewInt(-d), p), p)
+	if r == nil {
+		return nil, nil
+	}
+	if new(big.Int).Lsh(r, 1).Cmp(p) < 0 {
+		r = r.Sub(p, r)
+	}
+	a, b := new(big.Int).Set(p), r
+	for new(big.Int).Mul(b, b).Cmp(p) >= 0 {
+		a, b = b, a.Mod(a, b)
+	}
+	rest := new(big.Int).Sub(p, new(big.Int).Mul(b, b))
+	rest, m := rest.DivMod(rest, big.NewInt(d), new(big.Int))
+	if m.Sign() != 0 {
+		return nil, nil
+	}
+	y = bbig.SqrtBig(rest)
+	if new(big.Int).Mul(y, y).Cmp(rest) != 0 {
+		return nil, nil
+	}
+	return b, y
+}
+
+//cmOrder counts the points on a curve with j-invariant 0 (a = 0) or 1728
+//(b = 0). Their modular polynomials are degenerate, but the curves have
+//complex multiplication by Z[w] or Z[i], so Frobenius is an element of norm p
+//there and its trace is known up to a unit:
+//  p = x^2 + 3y^2 gives t in {+-2x, +-(x + 3y), +-(x - 3y)} for j = 0
+//  p = x^2 + y^2 gives t in {+-2x, +-2y} for j = 1728
+//Supersingular curves (p = 2 mod 3 or p = 3 mod 4) have t = 0. Random points
+//single out the candidate.
+func (curve shortWeierstrassCurve) cmOrder() (order *big.Int, err error) {
+
+	P := curve.P
+	d, m := int64(3), int64(3)
+	if new(big.Int).Mod(curve.A, P).Sign() != 0 {
+		d, m = 1, 4
+	}
+	traces := []*big.Int{big.NewInt(0)}
+	if new(big.Int).Mod(P, big.NewInt(m)).Int64() == 1 {
+		x, y := cornacchia(d, P)
+		if x == nil {
+			return nil, fmt.Errorf("could not write p as x^2 + %d*y^2", d)
+		}
+		if d == 3 {
+			y3 := new(big.Int).Mul(y, three)
+			traces = []*big.Int{new(big.Int).Lsh(x, 1), new(big.Int).Add(x, y3), new(big.Int).Sub(x, y3)}
+		} else {
+			traces = []*big.Int{new(big.Int).Lsh(x, 1), new(big.Int).Lsh(y, 1)}
+		}
+		for _, t := range traces {
+			traces = append(traces, new(big.Int).Neg(t))
+		}
+	}
+
+	candidates := make(map[string]*big.Int)
+	for _, t := range traces {
+		n := new(big.Int).Add(P, one)
+		n = n.Sub(n, t)
+		candidates[n.String()] = n
+	}
+	for attempt := 0; attempt < orderAttempts && len(candidates) > 1; attempt++ {
+		x, y := curve.randomPoint()
+		for key, n := range candidates {
+			if !curve.isZeroPoint(curve.ScalarMult(x, y, n.Bytes())) {
+				delete(candidates, key)
+			}
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("curve order is ambiguous")
+	}
+	for _, n := range candidates {
+		order = n
+	}
+	return order, nil
+}
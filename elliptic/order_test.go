@@ -0,0 +1,176 @@
+package elliptic
+
+import (
+	"math/big"
+	"testing"
+)
+
+//countPoints counts the points on a curve one x-coordinate at a time.
+func countPoints(curve shortWeierstrassCurve) *big.Int {
+	count := big.NewInt(1)
+	P := curve.P
+	for x := big.NewInt(0); x.Cmp(P) < 0; x = new(big.Int).Add(x, one) {
+		rhs := new(big.Int).Exp(x, three, P)
+		rhs = rhs.Add(rhs, new(big.Int).Mul(curve.A, x))
+		rhs = rhs.Add(rhs, curve.B)
+		rhs = rhs.Mod(rhs, P)
+		count = count.Add(count, big.NewInt(int64(1+big.Jacobi(rhs, P))))
+	}
+	return count
+}
+
+func TestOrderSmall(t *testing.T) {
+
+	tests := []struct{ a, b, p int64 }{
+		{2, 3, 97},
+		{0, 7, 101},
+		{-3, 5, 1009},
+		{1, 1, 10007},
+		{0, 1, 10007},
+	}
+	for i, test := range tests {
+		c := NewCurve(big.NewInt(test.a), big.NewInt(test.b), big.NewInt(test.p), zero, zero, zero)
+		expected := countPoints(c)
+		order, err := c.Order()
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			return
+		}
+		if order.Cmp(expected) != 0 {
+			t.Errorf("test %d: expected order %v, got %v", i, expected, order)
+			return
+		}
+	}
+
+	singular := NewCurve(big.NewInt(-3), big.NewInt(2), big.NewInt(97), zero, zero, zero)
+	if _, err := singular.Order(); err == nil {
+		t.Errorf("expected an error for a singular curve")
+		return
+	}
+}
+
+func TestQuadraticTwist(t *testing.T) {
+
+	c := NewCurve(big.NewInt(-3), big.NewInt(5), big.NewInt(1009), zero, zero, zero)
+	twist := c.quadraticTwist()
+	sum := new(big.Int).Add(countPoints(c), countPoints(twist))
+	if sum.Cmp(big.NewInt(2*1009+2)) != 0 {
+		t.Errorf("orders of the curve and its twist do not sum to 2p+2")
+		return
+	}
+}
+
+func TestOrderCryptopals(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("counting points on 128-bit curves is slow")
+	}
+
+	tests := []struct {
+		b     int64
+		order string
+	}{
+		{11279326, "233970423115425145498902418297807005944"},
+		{210, "233970423115425145550826547352470124412"},
+		{504, "233970423115425145544350131142039591210"},
+		{727, "233970423115425145545378039958152057148"},
+	}
+	for i, test := range tests {
+		c := NewCurve(a, big.NewInt(test.b), p, zero, gx, gy)
+		expected, _ := new(big.Int).SetString(test.order, 10)
+		order, err := c.Order()
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			return
+		}
+		if order.Cmp(expected) != 0 {
+			t.Errorf("test %d: expected order %v, got %v", i, expected, order)
+			return
+		}
+	}
+}
+
+func TestOrderSchoof(t *testing.T) {
+
+	//p = 2^48 - 59 is large enough for Order to use the SEA algorithm.
+	P := big.NewInt(281474976710597)
+	c := NewCurve(big.NewInt(-3), big.NewInt(1234567), P, zero, zero, zero)
+	order, err := c.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for i := 0; i < 10; i++ {
+		x, y := c.randomPoint()
+		x, y = c.ScalarMult(x, y, order.Bytes())
+		if !c.isZeroPoint(x, y) {
+			t.Errorf("order does not annihilate a random point")
+			return
+		}
+	}
+	twistOrder, err := c.quadraticTwist().Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	sum := new(big.Int).Add(order, twistOrder)
+	if sum.Cmp(new(big.Int).Lsh(new(big.Int).Add(P, one), 1)) != 0 {
+		t.Errorf("orders of the curve and its twist do not sum to 2p+2")
+		return
+	}
+}
+
+func TestOrderP256(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("counting points on P-256 is slow")
+	}
+
+	//P-256 has cofactor 1, so its order is the order of the base point.
+	c := p256()
+	order, err := c.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if order.Cmp(c.N) != 0 {
+		t.Errorf("expected order %v, got %v", c.N, order)
+		return
+	}
+}
+
+func TestOrderCM(t *testing.T) {
+
+	//secp256k1 is y^2 = x^3 + 7, with j-invariant 0 and cofactor 1
+	P, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	N, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	c := NewCurve(zero, big.NewInt(7), P, N, zero, zero)
+	order, err := c.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if order.Cmp(N) != 0 {
+		t.Errorf("expected order %v, got %v", N, order)
+		return
+	}
+
+	//y^2 = x^3 + x has j-invariant 1728 and its twist is y^2 = x^3 + d^2*x
+	P = big.NewInt(281474976710597)
+	c = NewCurve(one, zero, P, zero, zero, zero)
+	order, err = c.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	twistOrder, err := c.quadraticTwist().Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	sum := new(big.Int).Add(order, twistOrder)
+	if sum.Cmp(new(big.Int).Lsh(new(big.Int).Add(P, one), 1)) != 0 {
+		t.Errorf("orders of the curve and its twist do not sum to 2p+2")
+		return
+	}
+}
@@ -0,0 +1,299 @@
+package elliptic
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+//ScalarMultStrategy selects the algorithm used by ScalarMultWith.
+type ScalarMultStrategy int
+
+const (
+	//DoubleAndAdd doubles for every bit of the scalar and adds for every set
+	//bit. The trace gives away every bit.
+	DoubleAndAdd ScalarMultStrategy = iota
+	//AlwaysAdd adds for every bit and throws the sum away for clear bits.
+	AlwaysAdd
+	//WNAF uses the width-w non-adjacent form of the scalar. The trace gives
+	//away the positions of the non-zero digits.
+	WNAF
+	//FixedWindow adds a precomputed multiple for every w bits of the scalar,
+	//including a dummy addition for zero windows.
+	FixedWindow
+	//MontgomeryLadder does one addition and one doubling for every bit.
+	MontgomeryLadder
+	//Comb is the fixed-base comb method. The scalar is split into w rows and
+	//one of 2^w precomputed points is added for every column, including a
+	//dummy addition for zero columns. The table is computed once per curve
+	//for the base point, so Comb only applies to the base point; other points
+	//fall back to FixedWindow.
+	Comb
+)
+
+//windowWidth is the width w used by the WNAF, FixedWindow and Comb strategies.
+const windowWidth = 4
+
+//Operation is a point operation recorded in a Trace.
+type Operation byte
+
+const (
+	OpDouble Operation = 'D'
+	OpAdd    Operation = 'A'
+)
+
+//Trace is the sequence of point operations done by a scalar multiplication,
+//which is what simple power analysis gets to see. Precomputation that only
+//depends on the point and not on the scalar is left out.
+type Trace []Operation
+
+//String returns the trace as a string of 'D' and 'A' characters.
+func (trace Trace) String() string {
+	b := make([]byte, len(trace))
+	for i, op := range trace {
+		b[i] = byte(op)
+	}
+	return string(b)
+}
+
+//ScalarMultWith returns k*(x1, y1) computed with the supplied strategy.
+func (curve shortWeierstrassCurve) ScalarMultWith(x1, y1 *big.Int, k []byte, strategy ScalarMultStrategy) (x, y *big.Int) {
+	strategy = curve.baseStrategy(x1, y1, strategy)
+	Q := curve.scalarMult(curve.toJacobian(x1, y1), new(big.Int).SetBytes(k), strategy, nil)
+	return curve.toAffine(Q)
+}
+
+//ScalarMultTrace returns k*(x1, y1) computed with the supplied strategy along
+//with the trace of doublings and additions it did.
+func (curve shortWeierstrassCurve) ScalarMultTrace(x1, y1 *big.Int, k []byte, strategy ScalarMultStrategy) (x, y *big.Int, trace Trace) {
+	trace = Trace{}
+	strategy = curve.baseStrategy(x1, y1, strategy)
+	Q := curve.scalarMult(curve.toJacobian(x1, y1), new(big.Int).SetBytes(k), strategy, &trace)
+	x, y = curve.toAffine(Q)
+	return
+}
+
+//baseStrategy returns FixedWindow in place of Comb unless (x1, y1) is the base
+//point, which is the only point with a comb table.
+func (curve shortWeierstrassCurve) baseStrategy(x1, y1 *big.Int, strategy ScalarMultStrategy) ScalarMultStrategy {
+	if strategy == Comb && !curve.PointEquals(x1, y1, curve.Gx, curve.Gy) {
+		return FixedWindow
+	}
+	return strategy
+}
+
+//combTable is the precomputed table for the Comb strategy. table[i] is the sum
+//of 2^(j*d)*G over the set bits j of i.
+type combTable struct {
+	d     int
+	table []jacobianPoint
+}
+
+//combCache holds the comb table of a curve. NewCurve gives every curve its
+//own cache, which is shared by copies of the curve value. The table is kept
+//with the parameters and A it was computed for, so a copy with different ones
+//computes its own table.
+type combCache struct {
+	sync.Mutex
+	params *elliptic.CurveParams
+	a      *big.Int
+	table  *combTable
+}
+
+//comb returns the comb table for the base point, computing it on first use.
+//Curves that were not made by NewCurve have no cache and compute the table
+//every time.
+func (curve shortWeierstrassCurve) comb() *combTable {
+	c := curve.combCache
+	if c == nil {
+		return curve.newCombTable()
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.table == nil || c.params != curve.CurveParams || c.a.Cmp(curve.A) != 0 {
+		c.params, c.a = curve.CurveParams, new(big.Int).Set(curve.A)
+		c.table = curve.newCombTable()
+	}
+	return c.table
+}
+
+//newCombTable computes the comb table for the base point. The column width d
+//is fixed by the order of the base point, or by the size of the field if the
+//order is not known, so it does not depend on the scalar.
+func (curve shortWeierstrassCurve) newCombTable() *combTable {
+	bits := curve.P.BitLen() + 1
+	if curve.N != nil && curve.N.Sign() != 0 {
+		bits = curve.N.BitLen()
+	}
+	d := (bits + windowWidth - 1) / windowWidth
+	rows := make([]jacobianPoint, windowWidth)
+	rows[0] = curve.toJacobian(curve.Gx, curve.Gy)
+	for j := 1; j < windowWidth; j++ {
+		rows[j] = rows[j-1]
+		for i := 0; i < d; i++ {
+			rows[j] = curve.jacobianDouble(rows[j])
+		}
+	}
+	table := make([]jacobianPoint, 1<<windowWidth)
+	table[0] = infinity()
+	for i := 1; i < len(table); i++ {
+		for j := 0; j < windowWidth; j++ {
+			if i>>uint(j)&1 == 1 {
+				table[i] = curve.jacobianAdd(table[i&^(1<<uint(j))], rows[j])
+				break
+			}
+		}
+	}
+	return &combTable{d: d, table: table}
+}
+
+//ScalarBaseMultWith returns k*(x1, y1) where (x1, y1) is the base point for
+//the supplied curve, computed with the supplied strategy.
+func (curve shortWeierstrassCurve) ScalarBaseMultWith(k []byte, strategy ScalarMultStrategy) (x, y *big.Int) {
+	return curve.ScalarMultWith(curve.Gx, curve.Gy, k, strategy)
+}
+
+//scalarMult computes K*P with the supplied strategy. If trace is not nil every
+//doubling and addition that depends on K is appended to it. Comb uses the base
+//point table, so P must be the base point; see baseStrategy.
+func (curve shortWeierstrassCurve) scalarMult(P jacobianPoint, K *big.Int, strategy ScalarMultStrategy, trace *Trace) jacobianPoint {
+
+	double := func(A jacobianPoint) jacobianPoint {
+		if trace != nil {
+			*trace = append(*trace, OpDouble)
+		}
+		return curve.jacobianDouble(A)
+	}
+	add := func(A, B jacobianPoint) jacobianPoint {
+		if trace != nil {
+			*trace = append(*trace, OpAdd)
+		}
+		return curve.jacobianAdd(A, B)
+	}
+
+	Q := infinity()
+	switch strategy {
+	case AlwaysAdd:
+		for i := K.BitLen() - 1; i >= 0; i-- {
+			Q = double(Q)
+			R := add(Q, P)
+			if K.Bit(i) == 1 {
+				Q = R
+			}
+		}
+
+	case WNAF:
+		//odd multiples P, 3P, ..., (2^(w-1) - 1)P
+		table := curve.oddMultiples(P, 1<<(windowWidth-2))
+		digits := wnaf(K, windowWidth)
+		for i := len(digits) - 1; i >= 0; i-- {
+			Q = double(Q)
+			if d := digits[i]; d > 0 {
+				Q = add(Q, table[d/2])
+			} else if d < 0 {
+				Q = add(Q, jacobianNeg(table[-d/2]))
+			}
+		}
+
+	case FixedWindow:
+		//table[i] = i*P
+		table := make([]jacobianPoint, 1<<windowWidth)
+		table[0] = infinity()
+		for i := 1; i < len(table); i++ {
+			table[i] = curve.jacobianAdd(table[i-1], P)
+		}
+		windows := (K.BitLen() + windowWidth - 1) / windowWidth
+		for j := windows - 1; j >= 0; j-- {
+			for i := 0; i < windowWidth; i++ {
+				Q = double(Q)
+			}
+			digit := 0
+			for i := windowWidth - 1; i >= 0; i-- {
+				digit = digit<<1 | int(K.Bit(j*windowWidth+i))
+			}
+			//adding table[0] is the dummy addition for a zero window
+			Q = add(Q, table[digit])
+		}
+
+	case MontgomeryLadder:
+		R := P
+		for i := K.BitLen() - 1; i >= 0; i-- {
+			if K.Bit(i) == 1 {
+				Q = add(Q, R)
+				R = double(R)
+			} else {
+				R = add(Q, R)
+				Q = double(Q)
+			}
+		}
+
+	case Comb:
+		//P is the base point. Reducing K by the order keeps it within the
+		//w*d bits covered by the table.
+		c := curve.comb()
+		if curve.N != nil && curve.N.Sign() != 0 {
+			K = new(big.Int).Mod(K, curve.N)
+		}
+		if K.BitLen() > windowWidth*c.d {
+			return curve.scalarMult(P, K, FixedWindow, trace)
+		}
+		for col := c.d - 1; col >= 0; col-- {
+			Q = double(Q)
+			index := 0
+			for j := windowWidth - 1; j >= 0; j-- {
+				index = index<<1 | int(K.Bit(j*c.d+col))
+			}
+			//adding table[0] is the dummy addition for a zero column
+			Q = add(Q, c.table[index])
+		}
+
+	default:
+		for i := K.BitLen() - 1; i >= 0; i-- {
+			Q = double(Q)
+			if K.Bit(i) == 1 {
+				Q = add(Q, P)
+			}
+		}
+	}
+	return Q
+}
+
+//oddMultiples returns P, 3P, 5P, ... with n entries.
+func (curve shortWeierstrassCurve) oddMultiples(P jacobianPoint, n int) []jacobianPoint {
+	table := make([]jacobianPoint, n)
+	table[0] = P
+	P2 := curve.jacobianDouble(P)
+	for i := 1; i < n; i++ {
+		table[i] = curve.jacobianAdd(table[i-1], P2)
+	}
+	return table
+}
+
+//jacobianNeg returns -P.
+func jacobianNeg(P jacobianPoint) jacobianPoint {
+	if P.Infinity {
+		return P
+	}
+	return jacobianPoint{X: P.X, Y: new(big.Int).Neg(P.Y), Z: P.Z}
+}
+
+//wnaf returns the width-w non-adjacent form of K, least significant digit
+//first. Every non-zero digit is odd and less than 2^(w-1) in absolute value,
+//and any w consecutive digits contain at most one non-zero digit.
+func wnaf(K *big.Int, w uint) (digits []int) {
+	k := new(big.Int).Set(K)
+	mask := big.NewInt(1<<w - 1)
+	for k.Sign() > 0 {
+		d := 0
+		if k.Bit(0) == 1 {
+			d = int(new(big.Int).And(k, mask).Int64())
+			if d >= 1<<(w-1) {
+				d -= 1 << w
+			}
+			k = k.Sub(k, big.NewInt(int64(d)))
+		}
+		digits = append(digits, d)
+		k = k.Rsh(k, 1)
+	}
+	return digits
+}
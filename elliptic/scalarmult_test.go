@@ -0,0 +1,151 @@
+package elliptic
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+var strategies = []ScalarMultStrategy{DoubleAndAdd, AlwaysAdd, WNAF, FixedWindow, MontgomeryLadder, Comb}
+
+func TestScalarMultStrategies(t *testing.T) {
+
+	scalars := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), big.NewInt(15), big.NewInt(16), hund, order}
+	for i := 0; i < 10; i++ {
+		k, _ := rand.Int(rand.Reader, order)
+		scalars = append(scalars, k)
+	}
+	for _, k := range scalars {
+		//k*G with the affine formulas
+		ex, ey := big.NewInt(0), big.NewInt(1)
+		for i := k.BitLen() - 1; i >= 0; i-- {
+			ex, ey = curve.Double(ex, ey)
+			if k.Bit(i) == 1 {
+				ex, ey = curve.Add(ex, ey, gx, gy)
+			}
+		}
+		for _, strategy := range strategies {
+			x, y := curve.ScalarMultWith(gx, gy, k.Bytes(), strategy)
+			if !curve.PointEquals(x, y, ex, ey) {
+				t.Errorf("strategy %d: wrong result for k = %v", strategy, k)
+				return
+			}
+			x, y = curve.ScalarBaseMultWith(k.Bytes(), strategy)
+			if !curve.PointEquals(x, y, ex, ey) {
+				t.Errorf("strategy %d: wrong base point result for k = %v", strategy, k)
+				return
+			}
+		}
+
+		//k*(100*G) for a point without a comb table
+		hx, hy := curve.ScalarMultWith(hundX, hundY, k.Bytes(), DoubleAndAdd)
+		for _, strategy := range strategies {
+			x, y := curve.ScalarMultWith(hundX, hundY, k.Bytes(), strategy)
+			if !curve.PointEquals(x, y, hx, hy) {
+				t.Errorf("strategy %d: wrong result for k*(100*G) with k = %v", strategy, k)
+				return
+			}
+		}
+	}
+}
+
+func TestScalarMultTrace(t *testing.T) {
+
+	k1, _ := new(big.Int).SetString("b0000000000000000000000000000001", 16)
+	k2, _ := new(big.Int).SetString("ffffffffffffffffffffffffffffffff", 16)
+
+	_, _, trace := curve.ScalarMultTrace(gx, gy, k1.Bytes(), DoubleAndAdd)
+	if trace.String() != "DADDADA"+strings.Repeat("D", 124)+"A" {
+		t.Errorf("unexpected double-and-add trace %v", trace)
+		return
+	}
+
+	//the regular strategies give the same trace for scalars of the same
+	//length
+	for _, strategy := range []ScalarMultStrategy{AlwaysAdd, FixedWindow, MontgomeryLadder} {
+		_, _, t1 := curve.ScalarMultTrace(gx, gy, k1.Bytes(), strategy)
+		_, _, t2 := curve.ScalarMultTrace(gx, gy, k2.Bytes(), strategy)
+		if t1.String() != t2.String() {
+			t.Errorf("strategy %d: trace depends on the scalar", strategy)
+			return
+		}
+	}
+
+	//the comb trace only depends on the order of the base point, not on the
+	//length of the scalar
+	_, _, t1 := curve.ScalarMultTrace(gx, gy, one.Bytes(), Comb)
+	_, _, t2 := curve.ScalarMultTrace(gx, gy, k2.Bytes(), Comb)
+	d := (order.BitLen() + windowWidth - 1) / windowWidth
+	if t1.String() != t2.String() || t1.String() != strings.Repeat("DA", d) {
+		t.Errorf("unexpected comb traces %v and %v", t1, t2)
+		return
+	}
+
+	//wNAF adds once per non-zero digit
+	k, _ := rand.Int(rand.Reader, order)
+	_, _, trace = curve.ScalarMultTrace(gx, gy, k.Bytes(), WNAF)
+	nonZero := 0
+	for _, d := range wnaf(k, windowWidth) {
+		if d != 0 {
+			nonZero++
+		}
+	}
+	if strings.Count(trace.String(), "A") != nonZero {
+		t.Errorf("wNAF trace has %d additions, expected %d", strings.Count(trace.String(), "A"), nonZero)
+		return
+	}
+}
+
+func TestCombTablePerCurve(t *testing.T) {
+
+	//a copy of the curve with a different A shares the cache but must not
+	//share the table, since doubling depends on A
+	k, _ := rand.Int(rand.Reader, order)
+	curve.ScalarMultWith(gx, gy, k.Bytes(), Comb)
+	other := curve
+	other.A = big.NewInt(7)
+	ex, ey := other.ScalarMultWith(gx, gy, k.Bytes(), DoubleAndAdd)
+	x, y := other.ScalarMultWith(gx, gy, k.Bytes(), Comb)
+	if !other.PointEquals(x, y, ex, ey) {
+		t.Errorf("comb used the table of a curve with a different A")
+		return
+	}
+
+	//the original curve gets its own table back
+	ex, ey = curve.ScalarMultWith(gx, gy, k.Bytes(), DoubleAndAdd)
+	x, y = curve.ScalarMultWith(gx, gy, k.Bytes(), Comb)
+	if !curve.PointEquals(x, y, ex, ey) {
+		t.Errorf("comb used the table of a curve with a different A")
+		return
+	}
+}
+
+func TestWNAF(t *testing.T) {
+
+	for i := 0; i < 20; i++ {
+		k, _ := rand.Int(rand.Reader, order)
+		digits := wnaf(k, windowWidth)
+		sum := new(big.Int)
+		for j := len(digits) - 1; j >= 0; j-- {
+			sum = sum.Lsh(sum, 1)
+			sum = sum.Add(sum, big.NewInt(int64(digits[j])))
+			if digits[j] != 0 {
+				if digits[j]%2 == 0 || digits[j] >= 1<<(windowWidth-1) || -digits[j] >= 1<<(windowWidth-1) {
+					t.Errorf("invalid wNAF digit %d", digits[j])
+					return
+				}
+				for l := j + 1; l < j+windowWidth && l < len(digits); l++ {
+					if digits[l] != 0 {
+						t.Errorf("wNAF digits are too close together")
+						return
+					}
+				}
+			}
+		}
+		if sum.Cmp(k) != 0 {
+			t.Errorf("wNAF digits do not add up to the scalar")
+			return
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package elliptic
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+//poly is a polynomial over F_p with little-endian coefficients. Polynomials
+//are kept trimmed so the last coefficient is non-zero and every coefficient
+//is reduced mod p. The zero polynomial is the empty slice.
+type poly []*big.Int
+
+//polyField implements arithmetic in F_p[x].
+type polyField struct {
+	p *big.Int
+}
+
+func (pf polyField) trim(a poly) poly {
+	for len(a) > 0 && a[len(a)-1].Sign() == 0 {
+		a = a[:len(a)-1]
+	}
+	return a
+}
+
+//constant returns the polynomial c.
+func (pf polyField) constant(c *big.Int) poly {
+	return pf.trim(poly{new(big.Int).Mod(c, pf.p)})
+}
+
+func (pf polyField) add(a, b poly) poly {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	c := make(poly, len(a))
+	for i := range a {
+		c[i] = new(big.Int).Set(a[i])
+		if i < len(b) {
+			c[i] = c[i].Add(c[i], b[i])
+			if c[i].Cmp(pf.p) >= 0 {
+				c[i] = c[i].Sub(c[i], pf.p)
+			}
+		}
+	}
+	return pf.trim(c)
+}
+
+func (pf polyField) neg(a poly) poly {
+	c := make(poly, len(a))
+	for i := range a {
+		c[i] = new(big.Int).Sub(pf.p, a[i])
+		c[i] = c[i].Mod(c[i], pf.p)
+	}
+	return pf.trim(c)
+}
+
+func (pf polyField) sub(a, b poly) poly {
+	return pf.add(a, pf.neg(b))
+}
+
+//scale multiplies every coefficient of a by c.
+func (pf polyField) scale(a poly, c *big.Int) poly {
+	r := make(poly, len(a))
+	for i := range a {
+		r[i] = new(big.Int).Mul(a[i], c)
+		r[i] = r[i].Mod(r[i], pf.p)
+	}
+	return pf.trim(r)
+}
+
+//mul multiplies two polynomials. Large products use Kronecker substitution:
+//the coefficients are packed into one big integer with enough room between
+//them that the integer product can be unpacked into the polynomial product.
+//This lets math/big do the heavy lifting with Karatsuba multiplication.
+func (pf polyField) mul(a, b poly) poly {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	if len(a) < 16 || len(b) < 16 {
+		c := make(poly, len(a)+len(b)-1)
+		for i := range c {
+			c[i] = new(big.Int)
+		}
+		t := new(big.Int)
+		for i := range a {
+			if a[i].Sign() == 0 {
+				continue
+			}
+			for j := range b {
+				c[i+j] = c[i+j].Add(c[i+j], t.Mul(a[i], b[j]))
+			}
+		}
+		for i := range c {
+			c[i] = c[i].Mod(c[i], pf.p)
+		}
+		return pf.trim(c)
+	}
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	//every coefficient of the product is less than n*p^2
+	width := (2*pf.p.BitLen() + bits.Len(uint(n)) + 7) / 8
+	pack := func(x poly) *big.Int {
+		buf := make([]byte, len(x)*width)
+		for i, c := range x {
+			end := len(buf) - i*width
+			c.FillBytes(buf[end-width : end])
+		}
+		return new(big.Int).SetBytes(buf)
+	}
+	product := new(big.Int).Mul(pack(a), pack(b))
+	buf := product.FillBytes(make([]byte, (len(a)+len(b)-1)*width))
+	c := make(poly, len(a)+len(b)-1)
+	for i := range c {
+		end := len(buf) - i*width
+		c[i] = new(big.Int).SetBytes(buf[end-width : end])
+		c[i] = c[i].Mod(c[i], pf.p)
+	}
+	return pf.trim(c)
+}
+
+//monic divides a by its leading coefficient.
+func (pf polyField) monic(a poly) poly {
+	if len(a) == 0 {
+		return a
+	}
+	inv := new(big.Int).ModInverse(a[len(a)-1], pf.p)
+	return pf.scale(a, inv)
+}
+
+//divMod returns the quotient and remainder of a divided by b using schoolbook
+//long division.
+func (pf polyField) divMod(a, b poly) (q, r poly) {
+	if len(b) == 0 {
+		panic("polynomial division by zero")
+	}
+	if len(a) < len(b) {
+		return nil, a
+	}
+	r = make(poly, len(a))
+	for i := range a {
+		r[i] = new(big.Int).Set(a[i])
+	}
+	q = make(poly, len(a)-len(b)+1)
+	inv := new(big.Int).ModInverse(b[len(b)-1], pf.p)
+	t := new(big.Int)
+	for i := len(q) - 1; i >= 0; i-- {
+		c := new(big.Int).Mul(r[i+len(b)-1], inv)
+		c = c.Mod(c, pf.p)
+		q[i] = c
+		if c.Sign() == 0 {
+			continue
+		}
+		for j := range b {
+			r[i+j] = r[i+j].Sub(r[i+j], t.Mul(c, b[j]))
+			r[i+j] = r[i+j].Mod(r[i+j], pf.p)
+		}
+	}
+	return pf.trim(q), pf.trim(r[:len(b)-1])
+}
+
+//gcd returns the monic greatest common divisor of a and b.
+func (pf polyField) gcd(a, b poly) poly {
+	for len(b) > 0 {
+		_, r := pf.divMod(a, b)
+		a, b = b, r
+	}
+	return pf.monic(a)
+}
+
+//polyRing implements arithmetic in F_p[x]/(h) for a monic h. Reduction uses
+//a precomputed inverse of the reversal of h so that reducing a product costs
+//two multiplications instead of a long division.
+type polyRing struct {
+	polyField
+	h    poly
+	hinv poly
+}
+
+func newPolyRing(pf polyField, h poly) *polyRing {
+	h = pf.monic(h)
+	ring := &polyRing{polyField: pf, h: h}
+	n := len(h) - 1
+	if n > 1 {
+		//Newton iteration for rev(h)^-1 mod x^(n-1)
+		rev := reverse(h, len(h))
+		inv := poly{big.NewInt(1)}
+		for prec := 1; prec < n-1; {
+			prec *= 2
+			if prec > n-1 {
+				prec = n - 1
+			}
+			//inv = inv * (2 - rev*inv) mod x^prec
+			e := truncate(pf.mul(truncate(rev, prec), inv), prec)
+			e = pf.sub(pf.constant(two), e)
+			inv = truncate(pf.mul(inv, e), prec)
+		}
+		ring.hinv = inv
+	}
+	return ring
+}
+
+//reverse returns the coefficients of a reversed as a polynomial of length n.
+func reverse(a poly, n int) poly {
+	r := make(poly, n)
+	for i := range r {
+		if n-1-i < len(a) {
+			r[i] = a[n-1-i]
+		} else {
+			r[i] = new(big.Int)
+		}
+	}
+	return r
+}
+
+//truncate returns a mod x^n.
+func truncate(a poly, n int) poly {
+	if len(a) > n {
+		a = a[:n]
+	}
+	for len(a) > 0 && a[len(a)-1].Sign() == 0 {
+		a = a[:len(a)-1]
+	}
+	return a
+}
+
+//reduce returns a mod h.
+func (ring *polyRing) reduce(a poly) poly {
+	n := len(ring.h) - 1
+	if len(a) <= n {
+		return a
+	}
+	m := len(a) - n
+	if m <= 16 || m > n-1 {
+		_, r := ring.divMod(a, ring.h)
+		return r
+	}
+	//q = rev(rev(a) * rev(h)^-1 mod x^m)
+	q := truncate(ring.mul(truncate(reverse(a, len(a)), m), truncate(ring.hinv, m)), m)
+	q = ring.trim(reverse(q, m))
+	return truncate(ring.sub(a, ring.mul(q, ring.h)), n)
+}
+
+func (ring *polyRing) mulMod(a, b poly) poly {
+	return ring.reduce(ring.mul(a, b))
+}
+
+//exp returns a^e mod h.
+func (ring *polyRing) exp(a poly, e *big.Int) poly {
+	result := ring.reduce(poly{big.NewInt(1)})
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		result = ring.mulMod(result, result)
+		if e.Bit(i) == 1 {
+			result = ring.mulMod(result, a)
+		}
+	}
+	return result
+}
+
+//isZero returns true if a is zero mod h.
+func (ring *polyRing) isZero(a poly) bool {
+	return len(ring.reduce(a)) == 0
+}
+
+//ringPoint is a point in Jacobian coordinates (X:Y:Z) on the curve
+//v^2 = u^3 + A*u + B over F_p[x]/(h).
+type ringPoint struct {
+	X, Y, Z poly
+}
+
+//ringCurve is the curve used to evaluate the Frobenius relation on l-torsion
+//points. A generic l-torsion point (x, y) of y^2 = f(x) has y outside of
+//F_p[x]/(h). Writing y = y*1 and mapping (x, y*Y) to (u, v) = (f*x, f^2*Y)
+//moves it onto v^2 = u^3 + a*f^2*u + b*f^3, where both coordinates are in the
+//ring. A is a*f^2.
+type ringCurve struct {
+	*polyRing
+	A poly
+}
+
+func (rc ringCurve) pointDouble(P ringPoint) ringPoint {
+	//S = 4*X*Y^2, M = 3*X^2 + A*Z^4
+	YY := rc.mulMod(P.Y, P.Y)
+	S := rc.scale(rc.mulMod(P.X, YY), big.NewInt(4))
+	ZZ := rc.mulMod(P.Z, P.Z)
+	M := rc.scale(rc.mulMod(P.X, P.X), three)
+	M = rc.add(M, rc.mulMod(rc.A, rc.mulMod(ZZ, ZZ)))
+	//X3 = M^2 - 2*S, Y3 = M*(S - X3) - 8*Y^4, Z3 = 2*Y*Z
+	X3 := rc.sub(rc.mulMod(M, M), rc.scale(S, two))
+	Y3 := rc.mulMod(M, rc.sub(S, X3))
+	Y3 = rc.sub(Y3, rc.scale(rc.mulMod(YY, YY), big.NewInt(8)))
+	Z3 := rc.scale(rc.mulMod(P.Y, P.Z), two)
+	return ringPoint{X3, Y3, Z3}
+}
+
+//pointAdd adds two points. The caller must make sure that the points have
+//different x-coordinates at every root of h.
+func (rc ringCurve) pointAdd(P, Q ringPoint) ringPoint {
+	Z1Z1 := rc.mulMod(P.Z, P.Z)
+	Z2Z2 := rc.mulMod(Q.Z, Q.Z)
+	U1 := rc.mulMod(P.X, Z2Z2)
+	U2 := rc.mulMod(Q.X, Z1Z1)
+	S1 := rc.mulMod(P.Y, rc.mulMod(Q.Z, Z2Z2))
+	S2 := rc.mulMod(Q.Y, rc.mulMod(P.Z, Z1Z1))
+	H := rc.sub(U2, U1)
+	R := rc.sub(S2, S1)
+	HH := rc.mulMod(H, H)
+	HHH := rc.mulMod(H, HH)
+	U1HH := rc.mulMod(U1, HH)
+	//X3 = R^2 - H^3 - 2*U1*H^2
+	X3 := rc.sub(rc.sub(rc.mulMod(R, R), HHH), rc.scale(U1HH, two))
+	//Y3 = R*(U1*H^2 - X3) - S1*H^3
+	Y3 := rc.sub(rc.mulMod(R, rc.sub(U1HH, X3)), rc.mulMod(S1, HHH))
+	//Z3 = H*Z1*Z2
+	Z3 := rc.mulMod(H, rc.mulMod(P.Z, Q.Z))
+	return ringPoint{X3, Y3, Z3}
+}
+
+//scalarMult returns k*P for 1 <= k < l. Every intermediate addition is
+//between jP and P with j != 1, -1 (mod l), so none of them degenerate.
+func (rc ringCurve) scalarMult(P ringPoint, k int) ringPoint {
+	Q := P
+	for i := bits.Len(uint(k)) - 2; i >= 0; i-- {
+		Q = rc.pointDouble(Q)
+		if k>>uint(i)&1 == 1 {
+			Q = rc.pointAdd(Q, P)
+		}
+	}
+	return Q
+}
+
+//sameX returns true if P and Q have the same x-coordinate at every root of h.
+func (rc ringCurve) sameX(P, Q ringPoint) bool {
+	return rc.isZero(rc.sub(rc.mulMod(P.X, rc.mulMod(Q.Z, Q.Z)), rc.mulMod(Q.X, rc.mulMod(P.Z, P.Z))))
+}
+
+//sameY returns true if P and Q have the same y-coordinate at every root of h.
+func (rc ringCurve) sameY(P, Q ringPoint) bool {
+	QZ3 := rc.mulMod(Q.Z, rc.mulMod(Q.Z, Q.Z))
+	PZ3 := rc.mulMod(P.Z, rc.mulMod(P.Z, P.Z))
+	return rc.isZero(rc.sub(rc.mulMod(P.Y, QZ3), rc.mulMod(Q.Y, PZ3)))
+}
+
+//divisionPolynomial returns the l-th division polynomial of the curve for an
+//odd l. Division polynomials are computed with the usual recurrences,
+//storing psi_n for even n as y*f_n so every f_n is a polynomial in x alone.
+func (curve shortWeierstrassCurve) divisionPolynomial(pf polyField, l int) poly {
+	P := curve.P
+	A := new(big.Int).Mod(curve.A, P)
+	B := new(big.Int).Mod(curve.B, P)
+	c := func(v *big.Int) *big.Int { return new(big.Int).Mod(v, P) }
+	mul := func(vs ...*big.Int) *big.Int {
+		r := big.NewInt(1)
+		for _, v := range vs {
+			r = r.Mul(r, v)
+		}
+		return r.Mod(r, P)
+	}
+	AA := mul(A, A)
+
+	F := pf.trim(poly{c(B), c(A), new(big.Int), big.NewInt(1)})
+	FF := pf.mul(F, F)
+
+	f := map[int]poly{
+		0: nil,
+		1: poly{big.NewInt(1)},
+		2: pf.constant(two),
+		//3x^4 + 6ax^2 + 12bx - a^2
+		3: pf.trim(poly{c(new(big.Int).Neg(AA)), mul(big.NewInt(12), B), mul(big.NewInt(6), A), new(big.Int), c(three)}),
+		//4(x^6 + 5ax^4 + 20bx^3 - 5a^2x^2 - 4abx - 8b^2 - a^3)
+		4: pf.scale(pf.trim(poly{
+			c(new(big.Int).Neg(new(big.Int).Add(mul(big.NewInt(8), B, B), mul(AA, A)))),
+			c(new(big.Int).Neg(mul(big.NewInt(4), A, B))),
+			c(new(big.Int).Neg(mul(big.NewInt(5), AA))),
+			mul(big.NewInt(20), B),
+			mul(big.NewInt(5), A),
+			new(big.Int),
+			big.NewInt(1),
+		}), big.NewInt(4)),
+	}
+	half := new(big.Int).ModInverse(two, P)
+
+	var get func(n int) poly
+	get = func(n int) poly {
+		if v, ok := f[n]; ok {
+			return v
+		}
+		m := n / 2
+		var r poly
+		if n%2 == 1 {
+			a := pf.mul(get(m+2), pf.mul(get(m), pf.mul(get(m), get(m))))
+			b := pf.mul(get(m-1), pf.mul(get(m+1), pf.mul(get(m+1), get(m+1))))
+			if m%2 == 0 {
+				a = pf.mul(FF, a)
+			} else {
+				b = pf.mul(FF, b)
+			}
+			r = pf.sub(a, b)
+		} else {
+			a := pf.mul(get(m+2), pf.mul(get(m-1), get(m-1)))
+			b := pf.mul(get(m-2), pf.mul(get(m+1), get(m+1)))
+			r = pf.scale(pf.mul(pf.sub(a, b), get(m)), half)
+		}
+		f[n] = r
+		return r
+	}
+	return get(l)
+}
+
+//traceModTwo returns the trace of Frobenius mod 2. The trace is even exactly
+//when the curve has a point of order 2, i.e. when x^3 + ax + b has a root in
+//F_p.
+func (curve shortWeierstrassCurve) traceModTwo(pf polyField) int64 {
+	F := pf.trim(poly{new(big.Int).Mod(curve.B, curve.P), new(big.Int).Mod(curve.A, curve.P), new(big.Int), big.NewInt(1)})
+	ring := newPolyRing(pf, F)
+	X := poly{new(big.Int), big.NewInt(1)}
+	xp := ring.exp(X, curve.P)
+	if len(pf.gcd(F, pf.sub(xp, X))) > 1 {
+		return 0
+	}
+	return 1
+}
+
+//traceModL returns the trace of Frobenius mod an odd prime l using Schoof's
+//algorithm. It finds the tau for which
+//  phi^2(P) + (p mod l)*P = tau*phi(P)
+//holds for the l-torsion points P. ok is false when phi^2(P) = +-(p mod l)*P
+//on all the l-torsion points that are left, in which case the caller should
+//move on to the next l.
+func (curve shortWeierstrassCurve) traceModL(pf polyField, h poly, l int) (tau int64, ok bool) {
+
+	P := curve.P
+	ring := newPolyRing(pf, h)
+	X := poly{new(big.Int), big.NewInt(1)}
+	F := ring.reduce(pf.trim(poly{new(big.Int).Mod(curve.B, P), new(big.Int).Mod(curve.A, P), new(big.Int), big.NewInt(1)}))
+	FF := ring.mulMod(F, F)
+	rc := ringCurve{ring, ring.scale(FF, new(big.Int).Mod(curve.A, P))}
+
+	//phi(x, y) = (x^p, y * f^((p-1)/2)) and phi^2 follows from applying it
+	//twice: (x^(p^2), y * (f^((p-1)/2))^(p+1)).
+	halfP := new(big.Int).Rsh(P, 1)
+	xp := ring.exp(X, P)
+	yp := ring.exp(F, halfP)
+	xp2 := ring.exp(xp, P)
+	yp2 := ring.exp(yp, new(big.Int).Add(P, one))
+
+	constOne := ring.reduce(poly{big.NewInt(1)})
+	toRing := func(x, y poly) ringPoint {
+		return ringPoint{ring.mulMod(F, x), ring.mulMod(FF, y), constOne}
+	}
+	base := toRing(X, constOne)
+	phi := toRing(xp, yp)
+	phi2 := toRing(xp2, yp2)
+
+	pbar := int(new(big.Int).Mod(P, big.NewInt(int64(l))).Int64())
+	Q := rc.scalarMult(base, pbar)
+
+	//Points where phi^2(P) and pbar*P share an x-coordinate need special
+	//treatment. Drop them from h if we can.
+	diff := ring.sub(ring.mulMod(phi2.X, ring.mulMod(Q.Z, Q.Z)), Q.X)
+	g := pf.gcd(h, diff)
+	if len(g) > 1 {
+		if len(g) == len(h) {
+			return 0, false
+		}
+		rest, _ := pf.divMod(h, g)
+		return curve.traceModL(pf, rest, l)
+	}
+
+	S := rc.pointAdd(phi2, Q)
+	R := phi
+	for t := 1; t <= (l-1)/2; t++ {
+		if t == 2 {
+			R = rc.pointDouble(phi)
+		} else if t > 2 {
+			R = rc.pointAdd(R, phi)
+		}
+		if rc.sameX(S, R) {
+			if rc.sameY(S, R) {
+				return int64(t), true
+			}
+			return int64(l - t), true
+		}
+	}
+	return 0, false
+}
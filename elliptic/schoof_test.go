@@ -0,0 +1,48 @@
+package elliptic
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDivisionPolynomial(t *testing.T) {
+
+	c := NewCurve(big.NewInt(1), big.NewInt(1), big.NewInt(10007), zero, zero, zero)
+	pf := polyField{c.P}
+	n := countPoints(c)
+	//every x-coordinate of a point of order 3 or 5 is a root of psi_3 or
+	//psi_5.
+	for _, l := range []int64{3, 5} {
+		psi := c.divisionPolynomial(pf, int(l))
+		if len(psi)-1 != int(l*l-1)/2 {
+			t.Errorf("psi_%d has degree %d", l, len(psi)-1)
+			return
+		}
+		found := 0
+		for i := 0; i < 200; i++ {
+			x, y := c.randomPoint()
+			x, y = c.ScalarMult(x, y, new(big.Int).Div(n, big.NewInt(l)).Bytes())
+			if c.isZeroPoint(x, y) {
+				continue
+			}
+			lx, ly := c.ScalarMult(x, y, big.NewInt(l).Bytes())
+			if !c.isZeroPoint(lx, ly) {
+				continue
+			}
+			found++
+			v := new(big.Int)
+			for j := len(psi) - 1; j >= 0; j-- {
+				v = v.Mul(v, x)
+				v = v.Add(v, psi[j])
+				v = v.Mod(v, c.P)
+			}
+			if v.Sign() != 0 {
+				t.Errorf("x-coordinate of a point of order %d is not a root of psi_%d", l, l)
+				return
+			}
+		}
+		if found == 0 {
+			t.Logf("no points of order %d found", l)
+		}
+	}
+}
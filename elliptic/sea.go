@@ -0,0 +1,618 @@
+package elliptic
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	bbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+//schoofMaxPrime is the largest Atkin prime whose trace is still computed with
+//the full division polynomial. Larger Atkin primes are skipped, since an Elkies
+//prime of twice the size is much cheaper than one of them.
+const schoofMaxPrime = 13
+
+//inverses returns the inverses of 0 < k < n mod p using
+//  1/k = -(p/k) * 1/(p mod k) (mod p)
+//so that only the first one costs a modular inversion. inverses[0] is unused.
+func inverses(n int, p *big.Int) []*big.Int {
+	inv := make([]*big.Int, n)
+	if n < 2 {
+		return inv
+	}
+	inv[1] = big.NewInt(1)
+	q, r := new(big.Int), new(big.Int)
+	for k := 2; k < n; k++ {
+		q.QuoRem(p, big.NewInt(int64(k)), r)
+		inv[k] = new(big.Int).Mul(q, inv[r.Int64()])
+		inv[k] = inv[k].Neg(inv[k])
+		inv[k] = inv[k].Mod(inv[k], p)
+	}
+	return inv
+}
+
+//etaPower returns the first n coefficients of A(x)^r mod p, where
+//A(x) = prod_{k>=1} (1 - x^k). A is zero outside of the pentagonal numbers, so
+//G = A^r satisfies A*G' = r*A'*G and each coefficient of G follows from a
+//sparse recurrence:
+//  k*g_k = sum_{i>=1} a_i * (r*i - (k - i)) * g_(k-i)
+//inv must hold the inverses of 0 < k < n.
+func etaPower(r, n int, p *big.Int, inv []*big.Int) []*big.Int {
+	type term struct{ e, c int64 }
+	var terms []term
+	for k := int64(1); k*(3*k-1)/2 < int64(n); k++ {
+		c := int64(1)
+		if k%2 == 1 {
+			c = -1
+		}
+		terms = append(terms, term{k * (3*k - 1) / 2, c})
+		if e := k * (3*k + 1) / 2; e < int64(n) {
+			terms = append(terms, term{e, c})
+		}
+	}
+	g := make([]*big.Int, n)
+	if n == 0 {
+		return g
+	}
+	g[0] = big.NewInt(1)
+	t, c := new(big.Int), new(big.Int)
+	for k := 1; k < n; k++ {
+		sum := new(big.Int)
+		for _, term := range terms {
+			if term.e > int64(k) {
+				break
+			}
+			c.SetInt64(term.c * (int64(r)*term.e - (int64(k) - term.e)))
+			sum = sum.Add(sum, t.Mul(c, g[int64(k)-term.e]))
+		}
+		sum = sum.Mul(sum, inv[k])
+		g[k] = sum.Mod(sum, p)
+	}
+	return g
+}
+
+//mulSeries returns the first n coefficients of a*b.
+func (pf polyField) mulSeries(a, b []*big.Int, n int) []*big.Int {
+	c := pf.mul(pf.trim(a), pf.trim(b))
+	r := make([]*big.Int, n)
+	for i := range r {
+		if i < len(c) {
+			r[i] = c[i]
+		} else {
+			r[i] = new(big.Int)
+		}
+	}
+	return r
+}
+
+//eval returns a(x).
+func (pf polyField) eval(a poly, x *big.Int) *big.Int {
+	v := new(big.Int)
+	for i := len(a) - 1; i >= 0; i-- {
+		v = v.Mul(v, x)
+		v = v.Add(v, a[i])
+		v = v.Mod(v, pf.p)
+	}
+	return v
+}
+
+//deriv returns the derivative of a.
+func (pf polyField) deriv(a poly) poly {
+	if len(a) < 2 {
+		return nil
+	}
+	d := make(poly, len(a)-1)
+	for i := range d {
+		d[i] = new(big.Int).Mul(a[i+1], big.NewInt(int64(i+1)))
+		d[i] = d[i].Mod(d[i], pf.p)
+	}
+	return pf.trim(d)
+}
+
+//root returns a root of a in F_p, or nil if there is none. The roots of a in
+//F_p are the roots of r = gcd(a, x^p - x), and r is split with
+//gcd(r, (x + d)^((p-1)/2) - 1) for d = 1, 2, ... until a linear factor is
+//left (Cantor-Zassenhaus).
+func (pf polyField) root(a poly) *big.Int {
+	X := poly{new(big.Int), big.NewInt(1)}
+	xp := newPolyRing(pf, a).exp(X, pf.p)
+	r := pf.gcd(a, pf.sub(xp, X))
+	halfP := new(big.Int).Rsh(pf.p, 1)
+	for d := int64(1); len(r) > 2 && d < 1000; d++ {
+		shift := pf.trim(poly{new(big.Int).Mod(big.NewInt(d), pf.p), big.NewInt(1)})
+		w := newPolyRing(pf, r).exp(shift, halfP)
+		g := pf.gcd(r, pf.sub(w, poly{big.NewInt(1)}))
+		if len(g) > 1 && len(g) < len(r) {
+			if q, _ := pf.divMod(r, g); len(q) < len(g) {
+				g = pf.monic(q)
+			}
+			r = g
+		}
+	}
+	if len(r) != 2 {
+		return nil
+	}
+	return new(big.Int).Mod(new(big.Int).Neg(r[0]), pf.p)
+}
+
+//modularPolynomial is Müller's canonical modular polynomial Phi(X, J) for a
+//prime l, reduced mod p. For a curve with j-invariant J its roots in X are the
+//values of
+//  f(tau) = l^s * (eta(l*tau)/eta(tau))^(2s)
+//at the l+1 curves l-isogenous to it, where s = 12/gcd(12, l-1). Unlike the
+//classical modular polynomial, which has degree l+1 in J and huge
+//coefficients, Phi only has degree v = s*(l-1)/12 in J.
+type modularPolynomial struct {
+	pf   polyField
+	l, s int
+	//sums[m-1] is the sum of the m-th powers of the roots as a polynomial in J
+	sums []poly
+}
+
+//newModularPolynomial computes the power sums of the roots of Phi from
+//q-expansions. With x = q^(1/l) and A(x) = prod_{k>=1} (1 - x^k), the roots
+//other than f are G(zeta^k * x) for the l-th roots of unity zeta^k, where
+//  G(x) = x^-v * (A(x)/A(x^l))^(2s)
+//Summing the m-th powers over k keeps l times the terms of G^m whose exponent
+//is divisible by l. The power sum is a modular function with a pole of order at
+//most v at the cusp, so the coefficients of q^-v, ..., q^0 determine it as a
+//polynomial of degree v in j. f^m vanishes at the cusp and does not contribute
+//to those coefficients.
+func newModularPolynomial(pf polyField, l int) *modularPolynomial {
+
+	P := pf.p
+	g := 12
+	for r := l - 1; r != 0; {
+		g, r = r, g%r
+	}
+	s := 12 / g
+	v := s * (l - 1) / 12
+	inv := inverses((l+1)*v+1, P)
+
+	//q*j = E4^3 / A(q)^24 with E4 = 1 + 240 * sum sigma_3(k) q^k
+	E4 := make([]*big.Int, v+1)
+	E4[0] = big.NewInt(1)
+	for k := 1; k <= v; k++ {
+		sigma := int64(0)
+		for d := int64(1); d <= int64(k); d++ {
+			if int64(k)%d == 0 {
+				sigma += d * d * d
+			}
+		}
+		E4[k] = new(big.Int).Mod(big.NewInt(240*sigma), P)
+	}
+	qj := pf.mulSeries(pf.mulSeries(E4, E4, v+1), E4, v+1)
+	qj = pf.mulSeries(qj, etaPower(-24, v+1, P, inverses(v+1, P)), v+1)
+	//jpow[r][i] is the coefficient of q^(i-r) in j^r
+	jpow := [][]*big.Int{make([]*big.Int, v+1)}
+	for i := range jpow[0] {
+		jpow[0][i] = new(big.Int)
+	}
+	jpow[0][0].SetInt64(1)
+	for r := 1; r <= v; r++ {
+		jpow = append(jpow, pf.mulSeries(jpow[r-1], qj, v+1))
+	}
+
+	mp := &modularPolynomial{pf: pf, l: l, s: s}
+	L := big.NewInt(int64(l))
+	t := new(big.Int)
+	for m := 1; m <= l+1; m++ {
+		top := m * v / l
+		powA := etaPower(2*s*m, m*v+1, P, inv)
+		powInv := etaPower(-2*s*m, top+1, P, inv)
+		//principal[a] is the coefficient of q^-a in the power sum, the
+		//product of A(q)^(-2sm) and the terms of x^(-mv) * A(x)^(2sm) at
+		//x^(-la).
+		principal := make([]*big.Int, v+1)
+		for a := range principal {
+			principal[a] = new(big.Int)
+			for k := 0; a+k <= top; k++ {
+				principal[a] = principal[a].Add(principal[a], t.Mul(powA[m*v-l*(a+k)], powInv[k]))
+			}
+			principal[a] = principal[a].Mul(principal[a], L)
+			principal[a] = principal[a].Mod(principal[a], P)
+		}
+		//peel off the powers of j from the highest pole down
+		sum := make(poly, v+1)
+		for r := v; r >= 0; r-- {
+			sum[r] = new(big.Int).Set(principal[r])
+			for i := 0; i <= r; i++ {
+				principal[i] = principal[i].Sub(principal[i], t.Mul(sum[r], jpow[r][r-i]))
+				principal[i] = principal[i].Mod(principal[i], P)
+			}
+		}
+		mp.sums = append(mp.sums, pf.trim(sum))
+	}
+	return mp
+}
+
+//evaluate returns Phi(X, J) and its first and second derivatives in J as
+//polynomials in X. The power sums are evaluated at J + e in F_p[e]/(e^3) and
+//turned into the coefficients of Phi with Newton's identities
+//  k*e_k = sum_{i=1}^k (-1)^(i-1) * e_(k-i) * p_i
+//so that the coefficient of e carries the first derivative and the
+//coefficient of e^2 half of the second.
+func (mp *modularPolynomial) evaluate(J *big.Int) (phi, phiJ, phiJJ poly) {
+
+	P := mp.pf.p
+	//a jet is c[0] + c[1]*e + c[2]*e^2
+	type jet [3]*big.Int
+	mul := func(x, y jet) jet {
+		var z jet
+		for k := range z {
+			z[k] = new(big.Int)
+			for i := 0; i <= k; i++ {
+				z[k] = z[k].Add(z[k], new(big.Int).Mul(x[i], y[k-i]))
+			}
+			z[k] = z[k].Mod(z[k], P)
+		}
+		return z
+	}
+
+	point := jet{new(big.Int).Mod(J, P), big.NewInt(1), new(big.Int)}
+	sums := make([]jet, len(mp.sums))
+	for m, sum := range mp.sums {
+		acc := jet{new(big.Int), new(big.Int), new(big.Int)}
+		for r := len(sum) - 1; r >= 0; r-- {
+			acc = mul(acc, point)
+			acc[0] = acc[0].Add(acc[0], sum[r])
+			acc[0] = acc[0].Mod(acc[0], P)
+		}
+		sums[m] = acc
+	}
+
+	n := mp.l + 1
+	inv := inverses(n+1, P)
+	e := []jet{{big.NewInt(1), new(big.Int), new(big.Int)}}
+	for k := 1; k <= n; k++ {
+		ek := jet{new(big.Int), new(big.Int), new(big.Int)}
+		for i := 1; i <= k; i++ {
+			term := mul(e[k-i], sums[i-1])
+			for c := range ek {
+				if i%2 == 1 {
+					ek[c] = ek[c].Add(ek[c], term[c])
+				} else {
+					ek[c] = ek[c].Sub(ek[c], term[c])
+				}
+			}
+		}
+		for c := range ek {
+			ek[c] = ek[c].Mul(ek[c], inv[k])
+			ek[c] = ek[c].Mod(ek[c], P)
+		}
+		e = append(e, ek)
+	}
+
+	//Phi = sum_k (-1)^k e_k X^(n-k)
+	phi, phiJ, phiJJ = make(poly, n+1), make(poly, n+1), make(poly, n+1)
+	for k := 0; k <= n; k++ {
+		c := e[k]
+		if k%2 == 1 {
+			c = jet{new(big.Int).Neg(c[0]), new(big.Int).Neg(c[1]), new(big.Int).Neg(c[2])}
+		}
+		phi[n-k] = new(big.Int).Mod(c[0], P)
+		phiJ[n-k] = new(big.Int).Mod(c[1], P)
+		phiJJ[n-k] = new(big.Int).Mod(new(big.Int).Lsh(c[2], 1), P)
+	}
+	return mp.pf.trim(phi), mp.pf.trim(phiJ), mp.pf.trim(phiJJ)
+}
+
+//weierstrassCoefficients returns c_1, ..., c_n of the Laurent series
+//  wp(z) = z^-2 + sum_{k>=1} c_k z^(2k)
+//of the Weierstrass function of y^2 = x^3 + a*x + b. c[0] is unused.
+func weierstrassCoefficients(a, b, p *big.Int, n int) []*big.Int {
+	c := make([]*big.Int, n+1)
+	inv := func(k int64) *big.Int { return new(big.Int).ModInverse(big.NewInt(k), p) }
+	for k := 1; k <= n; k++ {
+		switch k {
+		case 1:
+			c[k] = new(big.Int).Mul(new(big.Int).Neg(a), inv(5))
+		case 2:
+			c[k] = new(big.Int).Mul(new(big.Int).Neg(b), inv(7))
+		default:
+			//c_k = 3/((k-2)(2k+3)) * sum_{h=1}^{k-2} c_h c_(k-1-h)
+			sum := new(big.Int)
+			for h := 1; h <= k-2; h++ {
+				sum = sum.Add(sum, new(big.Int).Mul(c[h], c[k-1-h]))
+			}
+			c[k] = sum.Mul(sum, inv(int64((k-2)*(2*k+3))))
+			c[k] = c[k].Mul(c[k], three)
+		}
+		c[k] = c[k].Mod(c[k], p)
+	}
+	return c
+}
+
+//kernelPolynomial returns the monic polynomial of degree d = (l-1)/2 whose
+//roots are the x-coordinates of the kernel of an l-isogeny from
+//y^2 = x^3 + a*x + b to y^2 = x^3 + at*x + bt, where p1 is the sum of the
+//x-coordinates of the l-1 non-zero kernel points. By Velu's formulas the
+//Weierstrass functions of the two curves satisfy
+//  wpt(z) = wp(z) + sum_{Q != 0} (wp(z + Q) - wp(Q))
+//and comparing the coefficients of z^(2k) gives
+//  ct_k - c_k = sum_{Q != 0} wp^(2k)(Q)/(2k)!
+//Since wp^(2k) is a polynomial of degree k+1 in wp, this yields the power sums
+//of the x-coordinates one at a time, and Newton's identities turn the power
+//sums into the coefficients.
+func kernelPolynomial(pf polyField, l int, a, b, at, bt, p1 *big.Int) poly {
+
+	P := pf.p
+	d := (l - 1) / 2
+	c := weierstrassCoefficients(a, b, P, d)
+	ct := weierstrassCoefficients(at, bt, P, d)
+
+	//wp'^2 = F(wp) with F = 4x^3 + 4ax + 4b, so the derivatives of wp are
+	//wp^(2k) = D_k(wp) with D_0 = x and D_(k+1) = D_k''*F + D_k'*F'/2.
+	four := big.NewInt(4)
+	F := pf.trim(poly{new(big.Int).Mod(new(big.Int).Mul(four, b), P), new(big.Int).Mod(new(big.Int).Mul(four, a), P), new(big.Int), four})
+	halfF := pf.trim(poly{new(big.Int).Mod(new(big.Int).Mul(two, a), P), new(big.Int), big.NewInt(6)})
+	D := poly{new(big.Int), big.NewInt(1)}
+
+	//sums[i] is the sum of the i-th powers of the x-coordinates of the l-1
+	//non-zero kernel points
+	sums := []*big.Int{big.NewInt(int64(l - 1)), new(big.Int).Mod(p1, P)}
+	factorial := big.NewInt(1)
+	for k := 1; k < d; k++ {
+		D = pf.add(pf.mul(pf.deriv(pf.deriv(D)), F), pf.mul(pf.deriv(D), halfF))
+		factorial = factorial.Mul(factorial, big.NewInt(int64((2*k-1)*2*k)))
+		sum := new(big.Int).Sub(ct[k], c[k])
+		sum = sum.Mul(sum, factorial)
+		for i := 0; i <= k; i++ {
+			if i < len(D) {
+				sum = sum.Sub(sum, new(big.Int).Mul(D[i], sums[i]))
+			}
+		}
+		sum = sum.Mul(sum, new(big.Int).ModInverse(D[k+1], P))
+		sums = append(sums, sum.Mod(sum, P))
+	}
+
+	//each x-coordinate belongs to two kernel points
+	half := new(big.Int).ModInverse(two, P)
+	inv := inverses(d+1, P)
+	e := []*big.Int{big.NewInt(1)}
+	for k := 1; k <= d; k++ {
+		ek := new(big.Int)
+		for i := 1; i <= k; i++ {
+			term := new(big.Int).Mul(e[k-i], sums[i])
+			if i%2 == 1 {
+				ek = ek.Add(ek, term)
+			} else {
+				ek = ek.Sub(ek, term)
+			}
+		}
+		ek = ek.Mul(ek, half)
+		ek = ek.Mul(ek, inv[k])
+		e = append(e, ek.Mod(ek, P))
+	}
+	g := make(poly, d+1)
+	for k := 0; k <= d; k++ {
+		g[d-k] = new(big.Int).Set(e[k])
+		if k%2 == 1 {
+			g[d-k] = g[d-k].Sub(P, g[d-k]).Mod(g[d-k], P)
+		}
+	}
+	return pf.trim(g)
+}
+
+//elkiesKernel returns the kernel polynomial of an l-isogeny defined over F_p,
+//a factor of degree (l-1)/2 of the l-th division polynomial, or nil if there
+//is none (l is an Atkin prime) or the formulas degenerate. The curve must not
+//have j-invariant 0 or 1728.
+//
+//A root f0 of Phi(X, j) in F_p is the value of f for an isogeny defined over
+//F_p. With E4 = -a/3, E6 = -b/2 and the derivation D = q*d/dq, differentiating
+//Phi(f, j) = 0 gives D(f) from D(j) = -j*E6/E4, and
+//  D(f)/f = s/12 * (l*E2(l*tau) - E2(tau))
+//fixes the sum p1 of the kernel x-coordinates, which is l times that bracket.
+//Differentiating twice gives E4 of the isogenous curve (the quasi-modular E2
+//cancels out), f^(12/s) = l^12 * Delta(l*tau)/Delta(tau) gives its
+//discriminant and the Fricke involution f -> l^s/f on Phi gives the sign of
+//its E6.
+func (curve shortWeierstrassCurve) elkiesKernel(pf polyField, mp *modularPolynomial) poly {
+
+	P := curve.P
+	l, s := mp.l, mp.s
+	n := func(x int64) *big.Int { return big.NewInt(x) }
+	mul := func(vs ...*big.Int) *big.Int {
+		r := big.NewInt(1)
+		for _, v := range vs {
+			r = r.Mul(r, v)
+			r = r.Mod(r, P)
+		}
+		return r
+	}
+	add := func(vs ...*big.Int) *big.Int {
+		r := new(big.Int)
+		for _, v := range vs {
+			r = r.Add(r, v)
+		}
+		return r.Mod(r, P)
+	}
+	neg := func(v *big.Int) *big.Int { return new(big.Int).Mod(new(big.Int).Neg(v), P) }
+	inv := func(v *big.Int) *big.Int { return new(big.Int).ModInverse(new(big.Int).Mod(v, P), P) }
+
+	a := new(big.Int).Mod(curve.A, P)
+	b := new(big.Int).Mod(curve.B, P)
+	E4 := mul(neg(a), inv(three))
+	E6 := mul(neg(b), inv(two))
+	Delta := mul(add(mul(E4, E4, E4), neg(mul(E6, E6))), inv(n(1728)))
+	j := mul(E4, E4, E4, inv(Delta))
+
+	phi, phiJ, phiJJ := mp.evaluate(j)
+	f := pf.root(phi)
+	if f == nil || f.Sign() == 0 {
+		return nil
+	}
+	dF := pf.eval(pf.deriv(phi), f)
+	dJ := pf.eval(phiJ, f)
+	dFF := pf.eval(pf.deriv(pf.deriv(phi)), f)
+	dFJ := pf.eval(pf.deriv(phiJ), f)
+	dJJ := pf.eval(phiJJ, f)
+	if dF.Sign() == 0 {
+		return nil
+	}
+
+	Dj := neg(mul(j, E6, inv(E4)))
+	Df := neg(mul(Dj, dJ, inv(dF)))
+	Du := mul(Df, inv(f))
+	//delta = l*E2(l*tau) - E2(tau)
+	delta := mul(n(12), Du, inv(n(int64(s))))
+	p1 := mul(n(int64(l)), delta)
+
+	//D^2(j) = E2/6*D(j) + (2*E4*E6^2/3 + E4^4/2)/Delta, and D^2(f) brings in
+	//E2 through delta and E4 of the isogenous curve:
+	//  D^2(f) = f*(Du^2 + s/144*(2*E2*delta + delta^2 - l^2*E4t + E4))
+	//The E2 terms cancel by the first derivative of Phi(f, j) = 0.
+	sOver144 := mul(n(int64(s)), inv(n(144)))
+	rhs := add(
+		mul(dFF, Df, Df),
+		mul(two, dFJ, Df, Dj),
+		mul(dJJ, Dj, Dj),
+		mul(dF, f, add(mul(Du, Du), mul(sOver144, add(mul(delta, delta), E4)))),
+		mul(dJ, add(mul(two, E4, E6, E6, inv(three)), mul(E4, E4, E4, E4, inv(two))), inv(Delta)),
+	)
+	E4t := mul(rhs, inv(mul(dF, f, sOver144, n(int64(l*l)))))
+	DeltaT := mul(Delta, new(big.Int).Exp(f, n(int64(12/s)), P), inv(new(big.Int).Exp(n(int64(l)), n(12), P)))
+	if E4t.Sign() == 0 {
+		return nil
+	}
+	jt := mul(E4t, E4t, E4t, inv(DeltaT))
+	if jt.Sign() == 0 || jt.Cmp(n(1728)) == 0 {
+		return nil
+	}
+
+	//ft = l^s/f = f(-1/(l*tau)) is a root of Phi(X, jt)
+	ft := mul(new(big.Int).Exp(n(int64(l)), n(int64(s)), P), inv(f))
+	phiT, phiTJ, _ := mp.evaluate(jt)
+	if pf.eval(phiT, ft).Sign() != 0 {
+		return nil
+	}
+	dFt := pf.eval(pf.deriv(phiT), ft)
+	dJt := pf.eval(phiTJ, ft)
+	if dJt.Sign() == 0 {
+		return nil
+	}
+	//D(j(l*tau)) = -l*jt*E6t/E4t and D(ft) = -ft*Du
+	Djt := mul(ft, Du, dFt, inv(dJt))
+	E6t := neg(mul(E4t, Djt, inv(mul(n(int64(l)), jt))))
+
+	//Velu's isogeny lands on the curve scaled by l
+	l4 := new(big.Int).Exp(n(int64(l)), n(4), P)
+	at := neg(mul(three, l4, E4t))
+	bt := neg(mul(two, l4, n(int64(l*l)), E6t))
+	return kernelPolynomial(pf, l, a, b, at, bt, p1)
+}
+
+//eigenvalue returns the eigenvalue of Frobenius on the kernel of an
+//l-isogeny defined over F_p: the lambda with phi(P) = lambda*P for the points
+//P whose x-coordinates are the roots of the kernel polynomial g. Only phi
+//itself has to be computed, and only mod g. ok is false if no lambda matches.
+func (curve shortWeierstrassCurve) eigenvalue(pf polyField, g poly, l int) (lambda int64, ok bool) {
+
+	P := curve.P
+	ring := newPolyRing(pf, g)
+	X := ring.reduce(poly{new(big.Int), big.NewInt(1)})
+	F := ring.reduce(pf.trim(poly{new(big.Int).Mod(curve.B, P), new(big.Int).Mod(curve.A, P), new(big.Int), big.NewInt(1)}))
+	FF := ring.mulMod(F, F)
+	rc := ringCurve{ring, ring.scale(FF, new(big.Int).Mod(curve.A, P))}
+
+	constOne := ring.reduce(poly{big.NewInt(1)})
+	toRing := func(x, y poly) ringPoint {
+		return ringPoint{ring.mulMod(F, x), ring.mulMod(FF, y), constOne}
+	}
+	base := toRing(X, constOne)
+	phi := toRing(ring.exp(X, P), ring.exp(F, new(big.Int).Rsh(P, 1)))
+
+	R := base
+	for k := 1; k <= (l-1)/2; k++ {
+		if k == 2 {
+			R = rc.pointDouble(base)
+		} else if k > 2 {
+			R = rc.pointAdd(R, base)
+		}
+		if rc.sameX(R, phi) {
+			if rc.sameY(R, phi) {
+				return int64(k), true
+			}
+			return int64(l - k), true
+		}
+	}
+	return 0, false
+}
+
+//seaTrace computes the trace of Frobenius t modulo M, where M is a product of
+//small primes chosen so that M is at least target. This is Schoof's algorithm
+//with Elkies' improvement: for about half of the primes l, the Elkies primes,
+//the curve has an l-isogeny defined over F_p. Frobenius maps its kernel to
+//itself, so it acts there as multiplication by an eigenvalue lambda and
+//  t = lambda + p/lambda (mod l)
+//The kernel polynomial has degree (l-1)/2 instead of the (l^2-1)/2 of the
+//division polynomial, which is what makes 256-bit curves practical. The other primes, the Atkin primes, are skipped
+//unless they are small enough for the full division polynomial.
+//
+//Primes are tried in order of the cost of their modular polynomial, which
+//depends on l mod 12 as much as on l. Curves with j-invariant 0 or 1728 have
+//no usable modular polynomials and fall back to plain Schoof.
+func (curve shortWeierstrassCurve) seaTrace(target *big.Int) (t, M *big.Int, err error) {
+
+	P := curve.P
+	pf := polyField{P}
+	residues := []*big.Int{big.NewInt(curve.traceModTwo(pf))}
+	moduli := []*big.Int{big.NewInt(2)}
+	M = big.NewInt(2)
+
+	a := new(big.Int).Mod(curve.A, P)
+	b := new(big.Int).Mod(curve.B, P)
+	elkies := a.Sign() != 0 && b.Sign() != 0
+
+	var primes []int
+	for l := 3; l < 1000; l += 2 {
+		if big.NewInt(int64(l)).ProbablyPrime(0) && P.Cmp(big.NewInt(int64(l))) > 0 {
+			primes = append(primes, l)
+		}
+	}
+	if elkies {
+		//the q-expansions have (l+1)*v terms for each of l+1 power sums
+		cost := func(l int) int {
+			g := 12
+			for r := l - 1; r != 0; {
+				g, r = r, g%r
+			}
+			return l * l * (l - 1) / g
+		}
+		sort.SliceStable(primes, func(i, j int) bool { return cost(primes[i]) < cost(primes[j]) })
+	}
+
+	for _, l := range primes {
+		if M.Cmp(target) >= 0 {
+			break
+		}
+		var tau int64
+		ok := false
+		if elkies {
+			if g := curve.elkiesKernel(pf, newModularPolynomial(pf, l)); g != nil {
+				var lambda int64
+				if lambda, ok = curve.eigenvalue(pf, g, l); ok {
+					L := big.NewInt(int64(l))
+					tau = new(big.Int).ModInverse(big.NewInt(lambda), L).Int64()
+					tau = (lambda + new(big.Int).Mod(P, L).Int64()*tau) % int64(l)
+				}
+			}
+		}
+		if !ok && (!elkies || l <= schoofMaxPrime) {
+			tau, ok = curve.traceModL(pf, curve.divisionPolynomial(pf, l), l)
+		}
+		if !ok {
+			continue
+		}
+		residues = append(residues, big.NewInt(tau))
+		moduli = append(moduli, big.NewInt(int64(l)))
+		M = M.Mul(M, big.NewInt(int64(l)))
+	}
+	if M.Cmp(target) < 0 {
+		return nil, nil, fmt.Errorf("ran out of small primes")
+	}
+	t, M, err = bbig.CRT(residues, moduli)
+	return
+}
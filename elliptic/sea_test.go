@@ -0,0 +1,81 @@
+package elliptic
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSEATrace(t *testing.T) {
+
+	tests := []struct{ a, b, p int64 }{
+		{1, 1, 10007},
+		{-3, 5, 10009},
+		{5, 0, 10037},
+	}
+	for i, test := range tests {
+		c := NewCurve(big.NewInt(test.a), big.NewInt(test.b), big.NewInt(test.p), zero, zero, zero)
+		tr, M, err := c.seaTrace(big.NewInt(1000))
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			return
+		}
+		if M.Cmp(big.NewInt(1000)) < 0 {
+			t.Errorf("test %d: modulus %v is smaller than the target", i, M)
+			return
+		}
+		expected := new(big.Int).Add(c.P, one)
+		expected = expected.Sub(expected, countPoints(c))
+		expected = expected.Mod(expected, M)
+		if tr.Cmp(expected) != 0 {
+			t.Errorf("test %d: expected trace %v mod %v, got %v", i, expected, M, tr)
+			return
+		}
+	}
+}
+
+func TestElkiesKernel(t *testing.T) {
+
+	//p = 2^48 - 59
+	c := NewCurve(big.NewInt(-3), big.NewInt(1234567), big.NewInt(281474976710597), zero, zero, zero)
+	pf := polyField{c.P}
+	elkies := 0
+	for _, l := range []int{3, 5, 7, 11, 13, 17, 19, 23} {
+		g := c.elkiesKernel(pf, newModularPolynomial(pf, l))
+		if g == nil {
+			continue
+		}
+		elkies++
+		if len(g)-1 != (l-1)/2 {
+			t.Errorf("kernel polynomial for l = %d has degree %d", l, len(g)-1)
+			return
+		}
+		//the kernel is made of l-torsion points
+		_, r := pf.divMod(c.divisionPolynomial(pf, l), g)
+		if len(r) != 0 {
+			t.Errorf("kernel polynomial for l = %d does not divide psi_%d", l, l)
+			return
+		}
+	}
+	if elkies == 0 {
+		t.Errorf("found no Elkies primes")
+		return
+	}
+}
+
+func TestRoot(t *testing.T) {
+
+	pf := polyField{big.NewInt(10007)}
+	//(x - 3)(x - 5)(x^2 + 1) has two roots, x^2 + 1 has none since
+	//10007 = 3 (mod 4)
+	x2 := pf.trim(poly{big.NewInt(1), new(big.Int), big.NewInt(1)})
+	a := pf.mul(pf.mul(pf.trim(poly{big.NewInt(10004), big.NewInt(1)}), pf.trim(poly{big.NewInt(10002), big.NewInt(1)})), x2)
+	r := pf.root(a)
+	if r == nil || (r.Int64() != 3 && r.Int64() != 5) {
+		t.Errorf("expected a root of 3 or 5, got %v", r)
+		return
+	}
+	if r := pf.root(x2); r != nil {
+		t.Errorf("found a root %v of x^2 + 1", r)
+		return
+	}
+}
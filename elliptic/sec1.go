@@ -0,0 +1,133 @@
+package elliptic
+
+import (
+	"fmt"
+	"math/big"
+)
+
+//PointFormat is a SEC1 point encoding.
+type PointFormat int
+
+const (
+	//Uncompressed is 0x04 || x || y.
+	Uncompressed PointFormat = iota
+	//Compressed is 0x02 || x for an even y and 0x03 || x for an odd y.
+	Compressed
+	//Hybrid is 0x06 || x || y for an even y and 0x07 || x || y for an odd y.
+	Hybrid
+)
+
+//byteLen returns the length of an encoded field element.
+func (curve shortWeierstrassCurve) byteLen() int {
+	return (curve.P.BitLen() + 7) / 8
+}
+
+//MarshalPoint encodes (x, y) as described in SEC1 section 2.3.3. The point at
+//infinity is encoded as a single zero byte in every format.
+func (curve shortWeierstrassCurve) MarshalPoint(x, y *big.Int, format PointFormat) []byte {
+
+	if curve.isZeroPoint(x, y) {
+		return []byte{0}
+	}
+	size := curve.byteLen()
+	xr := new(big.Int).Mod(x, curve.P)
+	yr := new(big.Int).Mod(y, curve.P)
+
+	var out []byte
+	switch format {
+	case Compressed:
+		out = make([]byte, 1+size)
+		out[0] = 0x02 | byte(yr.Bit(0))
+	case Hybrid:
+		out = make([]byte, 1+2*size)
+		out[0] = 0x06 | byte(yr.Bit(0))
+	default:
+		out = make([]byte, 1+2*size)
+		out[0] = 0x04
+	}
+	xb := xr.Bytes()
+	copy(out[1+size-len(xb):1+size], xb)
+	if format != Compressed {
+		yb := yr.Bytes()
+		copy(out[1+2*size-len(yb):], yb)
+	}
+	return out
+}
+
+//UnmarshalPoint decodes a SEC1 encoded point (SEC1 section 2.3.4) and checks
+//it according to policy. Compressed points are decompressed with a modular
+//square root.
+//
+//With NoValidation the decoder is as lax as a careless implementation: it
+//does not check that coordinates are below p, that an uncompressed or hybrid
+//point is on the curve, or that the parity in a hybrid prefix matches y. The
+//stricter policies reject all of these with an *InvalidPointError. Malformed
+//encodings, such as an unknown prefix or the wrong length, are rejected under
+//every policy. So is a compressed point with prefix 0x03 whose y is zero,
+//which has no odd square root, with an *InvalidPointError.
+func (curve shortWeierstrassCurve) UnmarshalPoint(data []byte, policy ValidationPolicy) (x, y *big.Int, err error) {
+
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("empty point encoding")
+	}
+	size := curve.byteLen()
+	prefix := data[0]
+	invalid := func(reason string) error {
+		return &InvalidPointError{Policy: policy, Reason: reason}
+	}
+
+	switch prefix {
+	case 0x00:
+		if len(data) != 1 {
+			return nil, nil, fmt.Errorf("point at infinity with trailing data")
+		}
+		x, y = big.NewInt(0), big.NewInt(1)
+
+	case 0x02, 0x03:
+		if len(data) != 1+size {
+			return nil, nil, fmt.Errorf("compressed point has length %d, expected %d", len(data), 1+size)
+		}
+		x = new(big.Int).SetBytes(data[1:])
+		if policy != NoValidation && x.Cmp(curve.P) >= 0 {
+			return nil, nil, invalid("coordinates out of range")
+		}
+		x = x.Mod(x, curve.P)
+		//y^2 = x^3 + a*x + b
+		rhs := new(big.Int).Exp(x, three, curve.P)
+		rhs = rhs.Add(rhs, new(big.Int).Mul(curve.A, x))
+		rhs = rhs.Add(rhs, curve.B)
+		rhs = rhs.Mod(rhs, curve.P)
+		y = new(big.Int).ModSqrt(rhs, curve.P)
+		if y == nil {
+			return nil, nil, invalid("x is not the x-coordinate of a point on the curve")
+		}
+		if y.Bit(0) != uint(prefix&1) {
+			//-0 = 0 so there is no odd y to pick
+			if y.Sign() == 0 {
+				return nil, nil, invalid("y is zero but the prefix asks for an odd y")
+			}
+			y = y.Sub(curve.P, y)
+		}
+
+	case 0x04, 0x06, 0x07:
+		if len(data) != 1+2*size {
+			return nil, nil, fmt.Errorf("uncompressed point has length %d, expected %d", len(data), 1+2*size)
+		}
+		x = new(big.Int).SetBytes(data[1 : 1+size])
+		y = new(big.Int).SetBytes(data[1+size:])
+		if policy == NoValidation {
+			return x.Mod(x, curve.P), y.Mod(y, curve.P), nil
+		}
+		if prefix != 0x04 && y.Bit(0) != uint(prefix&1) {
+			return nil, nil, invalid("hybrid prefix does not match the parity of y")
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("unknown point encoding prefix 0x%02x", prefix)
+	}
+
+	if err = curve.ValidatePoint(x, y, policy); err != nil {
+		return nil, nil, err
+	}
+	return x, y, nil
+}
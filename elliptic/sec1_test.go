@@ -0,0 +1,168 @@
+package elliptic
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+)
+
+func TestPointEncodingRoundTrip(t *testing.T) {
+
+	formats := []PointFormat{Uncompressed, Compressed, Hybrid}
+	for name, c := range ecdsaCurves() {
+		for i := 0; i < 10; i++ {
+			x, y := c.randomPoint()
+			for _, format := range formats {
+				data := c.MarshalPoint(x, y, format)
+				dx, dy, err := c.UnmarshalPoint(data, OnCurveValidation)
+				if err != nil {
+					t.Errorf("%s: format %d: unexpected error: %v", name, format, err)
+					return
+				}
+				if !c.PointEquals(x, y, dx, dy) {
+					t.Errorf("%s: format %d: decoded the wrong point", name, format)
+					return
+				}
+			}
+		}
+	}
+
+	zx, zy, err := curve.UnmarshalPoint(curve.MarshalPoint(zero, one, Compressed), NoValidation)
+	if err != nil || !curve.isZeroPoint(zx, zy) {
+		t.Errorf("failed to round trip the point at infinity")
+		return
+	}
+}
+
+func TestPointEncodingP256(t *testing.T) {
+
+	//the encodings match crypto/elliptic
+	c := p256()
+	std := elliptic.P256()
+	for i := 0; i < 10; i++ {
+		x, y := c.randomPoint()
+		if !bytes.Equal(c.MarshalPoint(x, y, Uncompressed), elliptic.Marshal(std, x, y)) {
+			t.Errorf("uncompressed encoding does not match crypto/elliptic")
+			return
+		}
+		if !bytes.Equal(c.MarshalPoint(x, y, Compressed), elliptic.MarshalCompressed(std, x, y)) {
+			t.Errorf("compressed encoding does not match crypto/elliptic")
+			return
+		}
+	}
+}
+
+func TestPointDecodingStrictness(t *testing.T) {
+
+	x, y := curve.randomPoint()
+	size := curve.byteLen()
+	good := curve.MarshalPoint(x, y, Uncompressed)
+
+	//an invalid curve point, as used by pohligHellmanOnline
+	weak := NewCurve(a, big.NewInt(210), p, zero, zero, zero)
+	wx, wy := weak.randomPoint()
+	offCurve := curve.MarshalPoint(wx, wy, Uncompressed)
+
+	//a hybrid encoding that lies about the parity of y
+	wrongParity := curve.MarshalPoint(x, y, Hybrid)
+	wrongParity[0] ^= 1
+
+	//x + p still fits in 16 bytes for a small enough x
+	ox, oy := curve.randomPoint()
+	for new(big.Int).Add(ox, p).BitLen() > 8*size {
+		ox, oy = curve.randomPoint()
+	}
+	outOfRange := curve.MarshalPoint(ox, oy, Uncompressed)
+	copy(outOfRange[1:1+size], new(big.Int).Add(ox, p).Bytes())
+
+	wrongPrefix := append([]byte{}, good...)
+	wrongPrefix[0] = 0x05
+	short := good[:len(good)-1]
+	//an x with no point on the curve
+	var noPoint []byte
+	for nx := big.NewInt(1); ; nx = nx.Add(nx, one) {
+		rhs := new(big.Int).Exp(nx, three, p)
+		rhs = rhs.Add(rhs, new(big.Int).Mul(a, nx))
+		rhs = rhs.Add(rhs, b)
+		if big.Jacobi(rhs.Mod(rhs, p), p) == -1 {
+			noPoint = make([]byte, 1+size)
+			noPoint[0] = 0x02
+			copy(noPoint[1+size-len(nx.Bytes()):], nx.Bytes())
+			break
+		}
+	}
+
+	//the curve has order 8*order, so 4*order times a point has order 1 or 2
+	//and a point of order 2 has y = 0. 0x03 || x asks for an odd y, which
+	//would decompress to y = p.
+	var zeroY []byte
+	for zeroY == nil {
+		rx, ry := curve.randomPoint()
+		tx, ty := curve.ScalarMult(rx, ry, new(big.Int).Mul(big.NewInt(4), order).Bytes())
+		if !curve.isZeroPoint(tx, ty) && ty.Sign() == 0 {
+			zeroY = curve.MarshalPoint(tx, ty, Compressed)
+			zeroY[0] = 0x03
+		}
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		lax       bool
+		typedErrs bool
+	}{
+		{"off curve", offCurve, true, true},
+		{"wrong hybrid parity", wrongParity, true, true},
+		{"coordinate out of range", outOfRange, true, true},
+		{"wrong prefix", wrongPrefix, false, false},
+		{"wrong length", short, false, false},
+		{"x not on curve", noPoint, false, true},
+		{"odd zero y", zeroY, false, true},
+	}
+	for _, test := range tests {
+		_, _, err := curve.UnmarshalPoint(test.data, NoValidation)
+		if test.lax && err != nil {
+			t.Errorf("%s: the lax decoder rejected the point: %v", test.name, err)
+			return
+		}
+		if !test.lax && err == nil {
+			t.Errorf("%s: the lax decoder accepted the point", test.name)
+			return
+		}
+		for _, policy := range []ValidationPolicy{OnCurveValidation, SubgroupValidation} {
+			_, _, err = curve.UnmarshalPoint(test.data, policy)
+			if err == nil {
+				t.Errorf("%s: policy %d accepted the point", test.name, policy)
+				return
+			}
+			if _, ok := err.(*InvalidPointError); ok != test.typedErrs {
+				t.Errorf("%s: policy %d returned %v", test.name, policy, err)
+				return
+			}
+		}
+	}
+
+	//even the lax decoder has no odd y to return for y = 0
+	if _, _, err := curve.UnmarshalPoint(zeroY, NoValidation); err == nil {
+		t.Errorf("odd zero y: the lax decoder accepted the point")
+		return
+	} else if _, ok := err.(*InvalidPointError); !ok {
+		t.Errorf("odd zero y: the lax decoder returned %v", err)
+		return
+	}
+
+	//the off-curve point makes it to ECDH through the lax decoder
+	victim, _ := GenerateKey(curve)
+	px, py, _ := curve.UnmarshalPoint(offCurve, NoValidation)
+	sx, sy, err := ECDH(victim, px, py, NoValidation)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	ex, ey := weak.ScalarMult(wx, wy, victim.D.Bytes())
+	if !curve.PointEquals(sx, sy, ex, ey) {
+		t.Errorf("the off-curve point was not used as is")
+		return
+	}
+}
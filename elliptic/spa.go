@@ -0,0 +1,169 @@
+package elliptic
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+)
+
+//spaMaxMisreads is the largest number of misreads RecoverScalarFromTrace
+//corrects in each half of a trace. The work grows with the number of ways to
+//place that many misreads in half of the trace: for a 128-bit scalar two
+//misreads give about a thousand readings of each half and three give about
+//twenty thousand, which takes seconds per trace.
+const spaMaxMisreads = 2
+
+//NoisyTrace returns a copy of trace where every operation is independently
+//misread, a doubling as an addition or an addition as a doubling, with
+//probability misread. It models a simple power analysis measurement.
+func NoisyTrace(trace Trace, misread float64) Trace {
+	noisy := make(Trace, len(trace))
+	for i, op := range trace {
+		noisy[i] = op
+		if rand.Float64() < misread {
+			noisy[i] = flipOperation(op)
+		}
+	}
+	return noisy
+}
+
+func flipOperation(op Operation) Operation {
+	if op == OpDouble {
+		return OpAdd
+	}
+	return OpDouble
+}
+
+//parseTrace reads the bits of a DoubleAndAdd trace. A clean trace is a string
+//of "D" tokens for clear bits and "DA" tokens for set bits. ok is false if the
+//trace cannot be split into tokens.
+func parseTrace(trace Trace) (k *big.Int, bits int, ok bool) {
+	k = new(big.Int)
+	for i := 0; i < len(trace); i++ {
+		if trace[i] != OpDouble {
+			return nil, 0, false
+		}
+		k = k.Lsh(k, 1)
+		if i+1 < len(trace) && trace[i+1] == OpAdd {
+			k = k.SetBit(k, 0, 1)
+			i++
+		}
+		bits++
+	}
+	return k, bits, true
+}
+
+//traceReading is one way of reading part of a trace: the bits it gives and
+//how many of them there are.
+type traceReading struct {
+	k    *big.Int
+	bits int
+}
+
+//readings returns every distinct parse of trace after correcting at most
+//misreads operations. Operations before start are never corrected.
+func readings(trace Trace, start, misreads int) []traceReading {
+	seen := make(map[string]bool)
+	var found []traceReading
+	fixed := make(Trace, len(trace))
+	copy(fixed, trace)
+
+	var walk func(from, left int)
+	walk = func(from, left int) {
+		if k, bits, ok := parseTrace(fixed); ok {
+			key := fmt.Sprintf("%d:%x", bits, k)
+			if !seen[key] {
+				seen[key] = true
+				found = append(found, traceReading{k, bits})
+			}
+		}
+		if left == 0 {
+			return
+		}
+		for i := from; i < len(fixed); i++ {
+			fixed[i] = flipOperation(fixed[i])
+			walk(i+1, left-1)
+			fixed[i] = flipOperation(fixed[i])
+		}
+	}
+	walk(start, misreads)
+	return found
+}
+
+//RecoverScalarFromTrace recovers k from a possibly noisy trace of
+//k*(x, y) = (qx, qy) computed with the DoubleAndAdd strategy, which is what
+//ScalarMult uses. Every operation in the trace is assumed to be misread with
+//probability misread.
+//
+//A single misread changes how the rest of the trace splits into bits, so the
+//trace is cut in two halves at a doubling and each half is read with up to
+//twice the expected number of misreads corrected. That budget is capped at
+//spaMaxMisreads, so an error is returned if misread*len(trace) is larger. A
+//128-bit scalar gives a trace of about 190 operations, so only misread
+//probabilities up to about 1% are covered and noisier traces are rejected.
+//Below that roughly one in seven traces has more misreads in one half than
+//can be corrected and is not recovered.
+//
+//If the top half reads as h and the bottom half as l with b bits then
+//  k = h*2^b + l
+//and the halves are matched with baby-step giant-step: h*(2^b*(x, y)) for
+//every reading of the top half against (qx, qy) - l*(x, y) for every reading
+//of the bottom half.
+func (curve shortWeierstrassCurve) RecoverScalarFromTrace(trace Trace, misread float64, x, y, qx, qy *big.Int) (k *big.Int, err error) {
+
+	L := len(trace)
+	if L < 2 {
+		if k, _, ok := parseTrace(trace); ok {
+			return k, nil
+		}
+		return nil, fmt.Errorf("failed to read the trace")
+	}
+	expected := misread * float64(L)
+	if expected > spaMaxMisreads {
+		return nil, fmt.Errorf("%.1f expected misreads is more than the %d that can be corrected", expected, spaMaxMisreads)
+	}
+	misreads := int(math.Ceil(expected))
+
+	//cut at a doubling near the middle so both halves start a token
+	cut := L / 2
+	for cut < L-1 && trace[cut] != OpDouble {
+		cut++
+	}
+	top := readings(trace[:cut], 0, misreads)
+	bottom := readings(trace[cut:], 1, misreads)
+
+	key := func(x, y *big.Int) string {
+		return x.String() + "," + y.String()
+	}
+
+	//baby steps: h*(2^b*(x, y)) for every top reading and every b seen in
+	//the bottom readings
+	tables := make(map[int]map[string]*big.Int)
+	for _, low := range bottom {
+		if _, ok := tables[low.bits]; ok {
+			continue
+		}
+		bx, by := curve.ScalarMult(x, y, new(big.Int).Lsh(one, uint(low.bits)).Bytes())
+		table := make(map[string]*big.Int, len(top))
+		for _, high := range top {
+			hx, hy := curve.ScalarMult(bx, by, high.k.Bytes())
+			table[key(hx, hy)] = high.k
+		}
+		tables[low.bits] = table
+	}
+
+	//giant steps: (qx, qy) - l*(x, y) for every bottom reading
+	for _, low := range bottom {
+		lx, ly := curve.ScalarMult(x, y, low.k.Bytes())
+		if !curve.isZeroPoint(lx, ly) {
+			lx, ly = curve.invertPoint(lx, ly)
+		}
+		tx, ty := curve.Add(qx, qy, lx, ly)
+		if high, ok := tables[low.bits][key(tx, ty)]; ok {
+			k = new(big.Int).Lsh(high, uint(low.bits))
+			return k.Add(k, low.k), nil
+		}
+	}
+	return nil, fmt.Errorf("failed to recover the scalar from the trace")
+}
@@ -0,0 +1,83 @@
+package elliptic
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestNoisyTrace(t *testing.T) {
+
+	k, _ := rand.Int(rand.Reader, order)
+	_, _, trace := curve.ScalarMultTrace(gx, gy, k.Bytes(), DoubleAndAdd)
+	if NoisyTrace(trace, 0).String() != trace.String() {
+		t.Errorf("a trace without misreads changed")
+		return
+	}
+	flipped := NoisyTrace(trace, 1)
+	for i := range trace {
+		if flipped[i] == trace[i] {
+			t.Errorf("a trace with certain misreads was read correctly")
+			return
+		}
+	}
+}
+
+func TestRecoverScalarFromTrace(t *testing.T) {
+
+	//about 85% of scalars are recovered at a misread probability of 0.5% or
+	//1%. Recovering fewer than 11 of 20 (or 4 of 10) at that rate happens
+	//less than once in a thousand runs, so both noise levels share the bound.
+	tests := []struct {
+		misread float64
+		all     bool
+	}{
+		{0, true},
+		{0.005, false},
+		{0.01, false},
+	}
+	trials, bound := 20, 11
+	if testing.Short() {
+		trials, bound = 10, 4
+	}
+	for _, test := range tests {
+		required := bound
+		if test.all {
+			required = trials
+		}
+		recovered := 0
+		for i := 0; i < trials; i++ {
+			k, _ := rand.Int(rand.Reader, order)
+			qx, qy, trace := curve.ScalarMultTrace(gx, gy, k.Bytes(), DoubleAndAdd)
+			noisy := NoisyTrace(trace, test.misread)
+			found, err := curve.RecoverScalarFromTrace(noisy, test.misread, gx, gy, qx, qy)
+			if err == nil && found.Cmp(k) == 0 {
+				recovered++
+			}
+		}
+		t.Logf("misread probability %v: recovered %d out of %d scalars", test.misread, recovered, trials)
+		if recovered < required {
+			t.Errorf("misread probability %v: recovered %d out of %d scalars, expected at least %d", test.misread, recovered, trials, required)
+			return
+		}
+	}
+
+	//more misreads than can be corrected is an error
+	k, _ := rand.Int(rand.Reader, order)
+	qx, qy, trace := curve.ScalarMultTrace(gx, gy, k.Bytes(), DoubleAndAdd)
+	if _, err := curve.RecoverScalarFromTrace(NoisyTrace(trace, 0.05), 0.05, gx, gy, qx, qy); err == nil {
+		t.Errorf("expected an error for a misread probability of 5%%")
+		return
+	}
+}
+
+func TestRecoverScalarFromRegularTrace(t *testing.T) {
+
+	//an always-add trace looks like a scalar with every bit set
+	k, _ := rand.Int(rand.Reader, order)
+	qx, qy, trace := curve.ScalarMultTrace(gx, gy, k.Bytes(), AlwaysAdd)
+	found, err := curve.RecoverScalarFromTrace(trace, 0, gx, gy, qx, qy)
+	if err == nil && found.Cmp(k) == 0 {
+		t.Errorf("recovered the scalar from an always-add trace")
+		return
+	}
+}
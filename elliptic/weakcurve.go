@@ -0,0 +1,63 @@
+package elliptic
+
+import (
+	"fmt"
+	"math/big"
+
+	bbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+//smallFactorBound is the largest prime factor of a generated curve order that
+//the invalid curve attack will use. Each factor costs a linear search in
+//ComputeIndexWithinRange, so larger factors are not worth the time when more
+//curves can be generated instead.
+const smallFactorBound = 1 << 16
+
+//weakCurveAttempts is the number of values of b tried by weakCurves before
+//giving up.
+const weakCurveAttempts = 1000
+
+//weakCurves searches for curves y^2 = x^3 + a*x + b' over the same field as
+//curve by trying b' = 1, 2, 3, ... and counting points with Order. A curve is
+//kept if its order has odd prime factors below smallFactorBound that no
+//earlier curve contributed. The search stops once the product of the
+//collected factors is at least bound, so a scalar smaller than bound can be
+//recovered from the curves with pohligHellmanOnline. The returned curves have
+//their order set as N.
+func (curve shortWeierstrassCurve) weakCurves(bound *big.Int) (curves []shortWeierstrassCurve, err error) {
+
+	covered := big.NewInt(1)
+	used := make(map[int64]bool)
+	B := big.NewInt(0)
+	for attempt := 0; attempt < weakCurveAttempts; attempt++ {
+		if covered.Cmp(bound) >= 0 {
+			return curves, nil
+		}
+		B = new(big.Int).Add(B, one)
+		if new(big.Int).Mod(curve.B, curve.P).Cmp(B) == 0 {
+			continue
+		}
+		candidate := NewCurve(curve.A, B, curve.P, zero, zero, zero)
+		n, err := candidate.Order()
+		if err != nil {
+			continue
+		}
+		factors, _ := bbig.Factor(n, smallFactorBound)
+		contributed := false
+		for factor := range factors {
+			if factor == 2 || used[factor] {
+				continue
+			}
+			used[factor] = true
+			covered = covered.Mul(covered, big.NewInt(factor))
+			contributed = true
+		}
+		if contributed {
+			curves = append(curves, NewCurve(curve.A, B, curve.P, n, zero, zero))
+		}
+	}
+	if covered.Cmp(bound) >= 0 {
+		return curves, nil
+	}
+	return nil, fmt.Errorf("could not find enough weak curves")
+}
@@ -0,0 +1,102 @@
+package elliptic
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	bbig "github.com/kelbyludwig/badcrypto/big"
+)
+
+func TestWeakCurves(t *testing.T) {
+
+	P := big.NewInt(281474976710597)
+	victim := NewCurve(big.NewInt(-3), big.NewInt(1234567), P, zero, zero, zero)
+	n, err := victim.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	curves, err := victim.weakCurves(n)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	covered := big.NewInt(1)
+	used := make(map[int64]bool)
+	for i, c := range curves {
+		if c.A.Cmp(victim.A) != 0 || c.P.Cmp(P) != 0 || c.B.Cmp(victim.B) == 0 {
+			t.Errorf("curve %d is not an invalid curve for the victim", i)
+			return
+		}
+		x, y := c.randomPoint()
+		x, y = c.ScalarMult(x, y, c.N.Bytes())
+		if !c.isZeroPoint(x, y) {
+			t.Errorf("curve %d has the wrong order", i)
+			return
+		}
+		factors, _ := bbig.Factor(c.N, smallFactorBound)
+		for factor := range factors {
+			if factor != 2 && !used[factor] {
+				used[factor] = true
+				covered = covered.Mul(covered, big.NewInt(factor))
+			}
+		}
+	}
+	if covered.Cmp(n) < 0 {
+		t.Errorf("small factors of the curve orders do not cover the victim's order")
+		return
+	}
+}
+
+func TestPohligHellmanOnlineGeneratesCurves(t *testing.T) {
+
+	P := big.NewInt(281474976710597)
+	victim := NewCurve(big.NewInt(-3), big.NewInt(1234567), P, zero, zero, zero)
+	n, err := victim.Order()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	victim = NewCurve(victim.A, victim.B, P, n, zero, zero)
+	priv, _ := rand.Int(rand.Reader, n)
+
+	oracle := func(x, y *big.Int) (*big.Int, *big.Int) {
+		return victim.ScalarMult(x, y, priv.Bytes())
+	}
+	index, modulus, err := victim.pohligHellmanOnline(nil, oracle)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if modulus.Cmp(n) < 0 {
+		t.Errorf("recovered the key modulo %v, which is smaller than the order", modulus)
+		return
+	}
+	if index.Cmp(priv) != 0 {
+		t.Errorf("failed to recover private key")
+		return
+	}
+}
+
+func TestCryptopals59GeneratedCurves(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("generating weak 128-bit curves is slow")
+	}
+
+	priv, _ := rand.Int(rand.Reader, order)
+	oracle := func(x, y *big.Int) (*big.Int, *big.Int) {
+		return curve.ScalarMult(x, y, priv.Bytes())
+	}
+	index, _, err := curve.pohligHellmanOnline(nil, oracle)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if index.Cmp(priv) != 0 {
+		t.Errorf("failed to recover private key")
+		return
+	}
+}
@@ -0,0 +1,121 @@
+package elliptic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+//curve25519 and curve448 are the Montgomery curves from RFC 7748 section 4.
+//Only u-coordinates are used, so the base point's v-coordinate is left out.
+//N is the order of the curve: 8 and 4 times the order of the base point.
+var curve25519 montgomeryCurve
+var curve448 montgomeryCurve
+
+func init() {
+	p25519 := new(big.Int).Lsh(one, 255)
+	p25519 = p25519.Sub(p25519, big.NewInt(19))
+	n25519, _ := new(big.Int).SetString("27742317777372353535851937790883648493", 10)
+	n25519 = n25519.Add(n25519, new(big.Int).Lsh(one, 252))
+	n25519 = n25519.Lsh(n25519, 3)
+	curve25519 = NewMontgomeryCurve(big.NewInt(486662), one, p25519, n25519, big.NewInt(9), nil)
+
+	//p = 2^448 - 2^224 - 1
+	p448 := new(big.Int).Lsh(one, 448)
+	p448 = p448.Sub(p448, new(big.Int).Lsh(one, 224))
+	p448 = p448.Sub(p448, one)
+	n448, _ := new(big.Int).SetString("13818066809895115352007386748515426880336692474882178609894547503885", 10)
+	n448 = n448.Sub(new(big.Int).Lsh(one, 446), n448)
+	n448 = n448.Lsh(n448, 2)
+	curve448 = NewMontgomeryCurve(big.NewInt(156326), one, p448, n448, big.NewInt(5), nil)
+}
+
+const (
+	//X25519Size is the length of X25519 scalars and u-coordinates.
+	X25519Size = 32
+	//X448Size is the length of X448 scalars and u-coordinates.
+	X448Size = 56
+)
+
+//ErrZeroSharedSecret is returned by X25519 and X448 when the result is all
+//zero, which happens when the peer sends a point of small order.
+var ErrZeroSharedSecret = errors.New("montgomery: shared secret is all zero")
+
+//X25519Basepoint and X448Basepoint are the encoded u-coordinates of the base
+//points.
+var X25519Basepoint = encodeLittleEndian(big.NewInt(9), X25519Size)
+var X448Basepoint = encodeLittleEndian(big.NewInt(5), X448Size)
+
+//X25519 computes scalar*u on Curve25519 as described in RFC 7748 section 5.
+//The scalar is clamped and the top bit of u is masked before use.
+func X25519(scalar, u []byte) ([]byte, error) {
+	k, err := clampScalar(scalar, X25519Size)
+	if err != nil {
+		return nil, err
+	}
+	k[0] &= 248
+	k[31] &= 127
+	k[31] |= 64
+	return curve25519.rfc7748(k, u, X25519Size, 255)
+}
+
+//X448 computes scalar*u on Curve448 as described in RFC 7748 section 5. The
+//scalar is clamped before use.
+func X448(scalar, u []byte) ([]byte, error) {
+	k, err := clampScalar(scalar, X448Size)
+	if err != nil {
+		return nil, err
+	}
+	k[0] &= 252
+	k[55] |= 128
+	return curve448.rfc7748(k, u, X448Size, 448)
+}
+
+//clampScalar checks the length of scalar and returns a copy of it that can be
+//clamped.
+func clampScalar(scalar []byte, size int) ([]byte, error) {
+	if len(scalar) != size {
+		return nil, fmt.Errorf("scalar has length %d, expected %d", len(scalar), size)
+	}
+	k := make([]byte, size)
+	copy(k, scalar)
+	return k, nil
+}
+
+//rfc7748 decodes the clamped scalar k and the u-coordinate u, keeping the low
+//bits bits of u, runs the ladder and encodes the result. Non-canonical values
+//of u are reduced mod p.
+func (curve montgomeryCurve) rfc7748(k, u []byte, size int, bits uint) ([]byte, error) {
+	if len(u) != size {
+		return nil, fmt.Errorf("u-coordinate has length %d, expected %d", len(u), size)
+	}
+	x := decodeLittleEndian(u)
+	if uint(x.BitLen()) > bits {
+		x = x.SetBit(x, int(bits), 0)
+	}
+	x = x.Mod(x, curve.P)
+	w := curve.ScalarMult(x, decodeLittleEndian(k).Bytes())
+	if w.Sign() == 0 {
+		return nil, ErrZeroSharedSecret
+	}
+	return encodeLittleEndian(w, size), nil
+}
+
+//decodeLittleEndian converts a little-endian byte string to an integer.
+func decodeLittleEndian(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+//encodeLittleEndian encodes x as a little-endian byte string of length size.
+func encodeLittleEndian(x *big.Int, size int) []byte {
+	be := x.Bytes()
+	le := make([]byte, size)
+	for i := range be {
+		le[i] = be[len(be)-1-i]
+	}
+	return le
+}
@@ -0,0 +1,134 @@
+package elliptic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestRFC7748Vectors(t *testing.T) {
+
+	//RFC 7748 section 5.2
+	tests := []struct {
+		f                   func(scalar, u []byte) ([]byte, error)
+		scalar, u, expected string
+	}{
+		{X25519,
+			"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+			"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+			"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552"},
+		{X25519,
+			"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+			"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+			"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957"},
+		{X448,
+			"3d262fddf9ec8e88495266fea19a34d28882acef045104d0d1aae121700a779c984c24f8cdd78fbff44943eba368f54b29259a4f1c600ad3",
+			"06fce640fa3487bfda5f6cf2d5263f8aad88334cbd07437f020f08f9814dc031ddbdc38c19c6da2583fa5429db94ada18aa7a7fb4ef8a086",
+			"ce3e4ff95a60dc6697da1db1d85e6afbdf79b50a2412d7546d5f239fe14fbaadeb445fc66a01b0779d98223961111e21766282f73dd96b6f"},
+		{X448,
+			"203d494428b8399352665ddca42f9de8fef600908e0d461cb021f8c538345dd77c3e4806e25f46d3315c44e0a5b4371282dd2c8d5be3095f",
+			"0fbcc2f993cd56d3305b0b7d9e55d4c1a8fb5dbb52f8e9a1e9b6201b165d015894e56c4d3570bee52fe205e28a78b91cdfbde71ce8d157db",
+			"884a02576239ff7a2f2f63b2db6a9ff37047ac13568e1e30fe63c4a7ad1b3ee3a5700df34321d62077e63633c575c1c954514e99da7c179d"},
+	}
+	for i, test := range tests {
+		out, err := test.f(mustHex(test.scalar), mustHex(test.u))
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			return
+		}
+		if !bytes.Equal(out, mustHex(test.expected)) {
+			t.Errorf("test %d: expected %s, got %x", i, test.expected, out)
+			return
+		}
+	}
+}
+
+func TestRFC7748Iterated(t *testing.T) {
+
+	tests := []struct {
+		f           func(scalar, u []byte) ([]byte, error)
+		base        []byte
+		iterations  int
+		expected    string
+		onlyLongRun bool
+	}{
+		{X25519, X25519Basepoint, 1, "422c8e7a6227d7bca1350b3e2bb7279f7897b87bb6854b783c60e80311ae3079", false},
+		{X25519, X25519Basepoint, 1000, "684cf59ba83309552800ef566f2f4d3c1c3887c49360e3875f2eb94d99532c51", true},
+		{X448, X448Basepoint, 1, "3f482c8a9f19b01e6c46ee9711d9dc14fd4bf67af30765c2ae2b846a4d23a8cd0db897086239492caf350b51f833868b9bc2b3bca9cf4113", false},
+		{X448, X448Basepoint, 1000, "aa3b4749d55b9daf1e5b00288826c467274ce3ebbdd5c17b975e09d4af6c67cf10d087202db88286e2b79fceea3ec353ef54faa26e219f38", true},
+	}
+	for i, test := range tests {
+		if test.onlyLongRun && testing.Short() {
+			continue
+		}
+		k := append([]byte{}, test.base...)
+		u := append([]byte{}, test.base...)
+		for j := 0; j < test.iterations; j++ {
+			out, err := test.f(k, u)
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+				return
+			}
+			k, u = out, k
+		}
+		if !bytes.Equal(k, mustHex(test.expected)) {
+			t.Errorf("test %d: expected %s after %d iterations, got %x", i, test.expected, test.iterations, k)
+			return
+		}
+	}
+}
+
+func TestX25519DiffieHellman(t *testing.T) {
+
+	//RFC 7748 section 6.1
+	alice := mustHex("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	alicePub := mustHex("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	bob := mustHex("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	bobPub := mustHex("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared := mustHex("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	if out, _ := X25519(alice, X25519Basepoint); !bytes.Equal(out, alicePub) {
+		t.Errorf("wrong public key for alice")
+		return
+	}
+	if out, _ := X25519(bob, X25519Basepoint); !bytes.Equal(out, bobPub) {
+		t.Errorf("wrong public key for bob")
+		return
+	}
+	s1, _ := X25519(alice, bobPub)
+	s2, _ := X25519(bob, alicePub)
+	if !bytes.Equal(s1, shared) || !bytes.Equal(s2, shared) {
+		t.Errorf("shared secrets do not match the test vector")
+		return
+	}
+
+	//the high bit of u is masked
+	masked := append([]byte{}, bobPub...)
+	masked[31] |= 0x80
+	if out, _ := X25519(alice, masked); !bytes.Equal(out, shared) {
+		t.Errorf("the high bit of u was not masked")
+		return
+	}
+
+	//u = 0 has order one and u = 1 has order four
+	for _, u := range []byte{0, 1} {
+		point := make([]byte, X25519Size)
+		point[0] = u
+		if _, err := X25519(alice, point); err != ErrZeroSharedSecret {
+			t.Errorf("expected ErrZeroSharedSecret for u = %d, got %v", u, err)
+			return
+		}
+	}
+	if _, err := X25519(alice[:31], bobPub); err == nil {
+		t.Errorf("expected an error for a short scalar")
+		return
+	}
+}
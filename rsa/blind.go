@@ -0,0 +1,95 @@
+package rsa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+//randomBlindingFactor returns a random integer in [2, n) that is invertible
+//mod n.
+func randomBlindingFactor(n *big.Int) (r *big.Int, err error) {
+	one := big.NewInt(1)
+	for {
+		r, err = rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if r.Cmp(one) <= 0 {
+			continue
+		}
+		if new(big.Int).GCD(nil, nil, r, n).Cmp(one) == 0 {
+			return r, nil
+		}
+	}
+}
+
+//Blind blinds message for a Chaum blind signature under publicKey. The
+//message is treated as an unpadded integer less than N; callers that want a
+//secure scheme should hash and pad it first. The returned blinding factor r is
+//needed to unblind the signature and must be kept secret.
+func Blind(message []byte, publicKey *PublicKey) (blinded []byte, r *big.Int, err error) {
+	N := publicKey.N
+	m := new(big.Int).SetBytes(message)
+	if m.Cmp(N) >= 0 {
+		return nil, nil, fmt.Errorf("message is too large for the modulus")
+	}
+	r, err = randomBlindingFactor(N)
+	if err != nil {
+		return
+	}
+	//blinded = m * r^e (mod N)
+	b := new(big.Int).Exp(r, big.NewInt(publicKey.E), N)
+	b = b.Mul(b, m)
+	b = b.Mod(b, N)
+	blinded = b.Bytes()
+	return
+}
+
+//BlindSign signs a blinded message. The signer learns nothing about the
+//message that was blinded.
+func BlindSign(blinded []byte, privateKey *PrivateKey) (blindSignature []byte) {
+	return DecryptNoPadding(blinded, privateKey)
+}
+
+//Unblind removes the blinding factor r from blindSignature. The result is a
+//signature s with s^e = m (mod N) for the message that was blinded.
+func Unblind(blindSignature []byte, r *big.Int, publicKey *PublicKey) (signature []byte, err error) {
+	N := publicKey.N
+	rinv := new(big.Int).ModInverse(r, N)
+	if rinv == nil {
+		return nil, fmt.Errorf("blinding factor is not invertible")
+	}
+	//signature = blindSignature * r^-1 (mod N)
+	s := new(big.Int).SetBytes(blindSignature)
+	s = s.Mul(s, rinv)
+	s = s.Mod(s, N)
+	signature = s.Bytes()
+	for len(signature) < len(N.Bytes()) {
+		signature = append([]byte{0}, signature...)
+	}
+	return
+}
+
+//RecoverViaBlinding recovers the plaintext of an unpadded RSA ciphertext from
+//an oracle that will decrypt any ciphertext except the one we are after. The
+//ciphertext is blinded with a random s as c' = s^e * c (mod N), decrypted by
+//the oracle and then unblinded by multiplying by s^-1.
+func RecoverViaBlinding(ciphertext []byte, publicKey *PublicKey, decryptOracle func([]byte) ([]byte, error)) (plaintext []byte, err error) {
+	blinded, s, err := Blind(ciphertext, publicKey)
+	if err != nil {
+		return
+	}
+	blindPlaintext, err := decryptOracle(blinded)
+	if err != nil {
+		return
+	}
+	//Decrypting s^e * c gives s * m. Unblinding divides s back out.
+	N := publicKey.N
+	sinv := new(big.Int).ModInverse(s, N)
+	m := new(big.Int).SetBytes(blindPlaintext)
+	m = m.Mul(m, sinv)
+	m = m.Mod(m, N)
+	plaintext = m.Bytes()
+	return
+}
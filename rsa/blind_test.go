@@ -0,0 +1,46 @@
+package rsa
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBlindSignature(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+
+	message := []byte("one e-cash coin")
+	blinded, r, err := Blind(message, priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to blind message: %v", err)
+		return
+	}
+	if new(big.Int).SetBytes(blinded).Cmp(new(big.Int).SetBytes(message)) == 0 {
+		t.Errorf("blinded message was the message")
+		return
+	}
+
+	blindSig := BlindSign(blinded, priv)
+	sig, err := Unblind(blindSig, r, priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to unblind signature: %v", err)
+		return
+	}
+
+	//the unblinded signature is the signature the signer would have made
+	//over the message directly
+	direct := DecryptNoPadding(message, priv)
+	if string(sig) != string(direct) {
+		t.Errorf("unblinded signature did not match a direct signature")
+		return
+	}
+	recovered := EncryptNoPadding(sig, priv.PublicKey)
+	if new(big.Int).SetBytes(recovered).Cmp(new(big.Int).SetBytes(message)) != 0 {
+		t.Errorf("unblinded signature did not verify")
+		return
+	}
+}
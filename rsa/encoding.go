@@ -0,0 +1,251 @@
+package rsa
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+//oidRSAEncryption is the rsaEncryption algorithm identifier from PKCS#1.
+var oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+
+//pkcs1PublicKey mirrors the RSAPublicKey structure from RFC 8017 Appendix A.1.1.
+type pkcs1PublicKey struct {
+	N *big.Int
+	E *big.Int
+}
+
+//pkcs1PrivateKey mirrors the RSAPrivateKey structure from RFC 8017 Appendix
+//A.1.2. Only two-prime keys are supported.
+type pkcs1PrivateKey struct {
+	Version int
+	N       *big.Int
+	E       *big.Int
+	D       *big.Int
+	P       *big.Int
+	Q       *big.Int
+	Dp      *big.Int
+	Dq      *big.Int
+	Qinv    *big.Int
+}
+
+//algorithmIdentifier mirrors the X.509 AlgorithmIdentifier structure.
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+//subjectPublicKeyInfo mirrors the X.509 SubjectPublicKeyInfo structure.
+type subjectPublicKeyInfo struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+//pkcs8PrivateKey mirrors the PKCS#8 PrivateKeyInfo structure.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+//rsaAlgorithm is the AlgorithmIdentifier used for RSA keys. The parameters
+//field must be an explicit NULL.
+var rsaAlgorithm = algorithmIdentifier{
+	Algorithm:  oidRSAEncryption,
+	Parameters: asn1.NullRawValue,
+}
+
+//MarshalPKCS1PublicKey encodes publicKey as a PKCS#1 RSAPublicKey in DER form.
+func MarshalPKCS1PublicKey(publicKey *PublicKey) ([]byte, error) {
+	return asn1.Marshal(pkcs1PublicKey{
+		N: publicKey.N,
+		E: big.NewInt(publicKey.E),
+	})
+}
+
+//ParsePKCS1PublicKey decodes a PKCS#1 RSAPublicKey in DER form.
+func ParsePKCS1PublicKey(der []byte) (*PublicKey, error) {
+	var pub pkcs1PublicKey
+	rest, err := asn1.Unmarshal(der, &pub)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after public key")
+	}
+	if pub.N.Sign() <= 0 || pub.E.Sign() <= 0 {
+		return nil, fmt.Errorf("public key contains a non-positive value")
+	}
+	if !pub.E.IsInt64() {
+		return nil, fmt.Errorf("public exponent is too large")
+	}
+	return &PublicKey{N: pub.N, E: pub.E.Int64()}, nil
+}
+
+//MarshalPKCS1PrivateKey encodes privateKey as a PKCS#1 RSAPrivateKey in DER
+//form. The CRT values are computed from the key's two primes.
+func MarshalPKCS1PrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	if len(privateKey.Primes) != 2 {
+		return nil, fmt.Errorf("only two-prime keys are supported")
+	}
+	p := privateKey.Primes[0]
+	q := privateKey.Primes[1]
+	pm1 := new(big.Int).Sub(p, big.NewInt(1))
+	qm1 := new(big.Int).Sub(q, big.NewInt(1))
+	qinv := new(big.Int).ModInverse(q, p)
+	if qinv == nil {
+		return nil, fmt.Errorf("primes are not coprime")
+	}
+	return asn1.Marshal(pkcs1PrivateKey{
+		N:    privateKey.PublicKey.N,
+		E:    big.NewInt(privateKey.PublicKey.E),
+		D:    privateKey.D,
+		P:    p,
+		Q:    q,
+		Dp:   new(big.Int).Mod(privateKey.D, pm1),
+		Dq:   new(big.Int).Mod(privateKey.D, qm1),
+		Qinv: qinv,
+	})
+}
+
+//ParsePKCS1PrivateKey decodes a PKCS#1 RSAPrivateKey in DER form. The CRT
+//values in the encoding are ignored.
+func ParsePKCS1PrivateKey(der []byte) (*PrivateKey, error) {
+	var priv pkcs1PrivateKey
+	rest, err := asn1.Unmarshal(der, &priv)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if priv.Version != 0 {
+		return nil, fmt.Errorf("unsupported private key version %d", priv.Version)
+	}
+	if priv.N.Sign() <= 0 || priv.E.Sign() <= 0 || priv.D.Sign() <= 0 ||
+		priv.P.Sign() <= 0 || priv.Q.Sign() <= 0 {
+		return nil, fmt.Errorf("private key contains a non-positive value")
+	}
+	if !priv.E.IsInt64() {
+		return nil, fmt.Errorf("public exponent is too large")
+	}
+	if new(big.Int).Mul(priv.P, priv.Q).Cmp(priv.N) != 0 {
+		return nil, fmt.Errorf("primes do not multiply to the modulus")
+	}
+	return &PrivateKey{
+		PublicKey: &PublicKey{N: priv.N, E: priv.E.Int64()},
+		D:         priv.D,
+		Primes:    []*big.Int{priv.P, priv.Q},
+	}, nil
+}
+
+//MarshalPKIXPublicKey encodes publicKey as an X.509 SubjectPublicKeyInfo in
+//DER form.
+func MarshalPKIXPublicKey(publicKey *PublicKey) ([]byte, error) {
+	der, err := MarshalPKCS1PublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: rsaAlgorithm,
+		PublicKey: asn1.BitString{Bytes: der, BitLength: 8 * len(der)},
+	})
+}
+
+//ParsePKIXPublicKey decodes an X.509 SubjectPublicKeyInfo in DER form that
+//holds an RSA public key.
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after public key")
+	}
+	if !spki.Algorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, fmt.Errorf("public key is not an RSA key")
+	}
+	return ParsePKCS1PublicKey(spki.PublicKey.RightAlign())
+}
+
+//MarshalPKCS8PrivateKey encodes privateKey as a PKCS#8 PrivateKeyInfo in DER
+//form.
+func MarshalPKCS8PrivateKey(privateKey *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS1PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8PrivateKey{
+		Algorithm:  rsaAlgorithm,
+		PrivateKey: der,
+	})
+}
+
+//ParsePKCS8PrivateKey decodes a PKCS#8 PrivateKeyInfo in DER form that holds
+//an RSA private key.
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var info pkcs8PrivateKey
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return ParsePKCS1PrivateKey(info.PrivateKey)
+}
+
+//MarshalPublicKeyPEM encodes publicKey as a PEM "PUBLIC KEY" block.
+func MarshalPublicKeyPEM(publicKey *PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+//MarshalPrivateKeyPEM encodes privateKey as a PEM "RSA PRIVATE KEY" block.
+func MarshalPrivateKeyPEM(privateKey *PrivateKey) ([]byte, error) {
+	der, err := MarshalPKCS1PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}), nil
+}
+
+//ParsePublicKeyPEM decodes the first PEM block in data. Both "PUBLIC KEY"
+//(PKIX) and "RSA PUBLIC KEY" (PKCS#1) blocks are accepted.
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
+
+//ParsePrivateKeyPEM decodes the first PEM block in data. Both "RSA PRIVATE
+//KEY" (PKCS#1) and "PRIVATE KEY" (PKCS#8) blocks are accepted.
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return ParsePKCS8PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
@@ -0,0 +1,160 @@
+package rsa
+
+import (
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func TestPKCS1RoundTripStdlib(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+
+	der, err := MarshalPKCS1PrivateKey(priv)
+	if err != nil {
+		t.Errorf("failed to marshal private key: %v", err)
+		return
+	}
+	stdPriv, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		t.Errorf("stdlib failed to parse private key: %v", err)
+		return
+	}
+	if stdPriv.N.Cmp(priv.PublicKey.N) != 0 || stdPriv.D.Cmp(priv.D) != 0 || int64(stdPriv.E) != priv.PublicKey.E {
+		t.Errorf("stdlib private key did not match")
+		return
+	}
+
+	parsed, err := ParsePKCS1PrivateKey(x509.MarshalPKCS1PrivateKey(stdPriv))
+	if err != nil {
+		t.Errorf("failed to parse stdlib private key: %v", err)
+		return
+	}
+	if parsed.D.Cmp(priv.D) != 0 || parsed.PublicKey.N.Cmp(priv.PublicKey.N) != 0 {
+		t.Errorf("parsed private key did not match")
+		return
+	}
+
+	pubDer, err := MarshalPKCS1PublicKey(priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to marshal public key: %v", err)
+		return
+	}
+	stdPub, err := x509.ParsePKCS1PublicKey(pubDer)
+	if err != nil {
+		t.Errorf("stdlib failed to parse public key: %v", err)
+		return
+	}
+	pub, err := ParsePKCS1PublicKey(x509.MarshalPKCS1PublicKey(stdPub))
+	if err != nil {
+		t.Errorf("failed to parse stdlib public key: %v", err)
+		return
+	}
+	if pub.N.Cmp(priv.PublicKey.N) != 0 || pub.E != priv.PublicKey.E {
+		t.Errorf("parsed public key did not match")
+		return
+	}
+}
+
+func TestPKIXAndPKCS8RoundTripStdlib(t *testing.T) {
+
+	stdPriv, err := stdrsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Errorf("failed to generate stdlib key")
+		return
+	}
+
+	pubDer, err := x509.MarshalPKIXPublicKey(&stdPriv.PublicKey)
+	if err != nil {
+		t.Errorf("stdlib failed to marshal public key: %v", err)
+		return
+	}
+	pub, err := ParsePKIXPublicKey(pubDer)
+	if err != nil {
+		t.Errorf("failed to parse stdlib public key: %v", err)
+		return
+	}
+	ourDer, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Errorf("failed to marshal public key: %v", err)
+		return
+	}
+	if string(ourDer) != string(pubDer) {
+		t.Errorf("PKIX encoding did not match stdlib encoding")
+		return
+	}
+
+	privDer, err := x509.MarshalPKCS8PrivateKey(stdPriv)
+	if err != nil {
+		t.Errorf("stdlib failed to marshal private key: %v", err)
+		return
+	}
+	priv, err := ParsePKCS8PrivateKey(privDer)
+	if err != nil {
+		t.Errorf("failed to parse stdlib private key: %v", err)
+		return
+	}
+	ourDer, err = MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Errorf("failed to marshal private key: %v", err)
+		return
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(ourDer)
+	if err != nil {
+		t.Errorf("stdlib failed to parse private key: %v", err)
+		return
+	}
+	stdParsed, ok := parsed.(*stdrsa.PrivateKey)
+	if !ok || !stdParsed.Equal(stdPriv) {
+		t.Errorf("stdlib parsed a different private key")
+		return
+	}
+}
+
+func TestPEMRoundTrip(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+
+	pubPEM, err := MarshalPublicKeyPEM(priv.PublicKey)
+	if err != nil {
+		t.Errorf("failed to marshal public key: %v", err)
+		return
+	}
+	pub, err := ParsePublicKeyPEM(pubPEM)
+	if err != nil {
+		t.Errorf("failed to parse public key: %v", err)
+		return
+	}
+	if pub.N.Cmp(priv.PublicKey.N) != 0 || pub.E != priv.PublicKey.E {
+		t.Errorf("parsed public key did not match")
+		return
+	}
+
+	privPEM, err := MarshalPrivateKeyPEM(priv)
+	if err != nil {
+		t.Errorf("failed to marshal private key: %v", err)
+		return
+	}
+	parsed, err := ParsePrivateKeyPEM(privPEM)
+	if err != nil {
+		t.Errorf("failed to parse private key: %v", err)
+		return
+	}
+
+	message := []byte("round trip")
+	ct := EncryptNoPadding(message, pub)
+	pt := DecryptNoPadding(ct, parsed)
+	if string(pt[len(pt)-len(message):]) != string(message) {
+		t.Errorf("parsed keys did not decrypt correctly")
+		return
+	}
+}
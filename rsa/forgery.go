@@ -0,0 +1,372 @@
+package rsa
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+)
+
+//PKCS1v15Flaw identifies an implementation flaw in a PKCS1v15 signature
+//verifier that allows e=3 signatures to be forged.
+type PKCS1v15Flaw int
+
+const (
+	//FlawGarbageAfterHash is a verifier that parses the encoding from left to
+	//right and never checks that the digest ends the block. This is the
+	//original flaw described by Bleichenbacher. See verifyPKCS1v15Insecure.
+	FlawGarbageAfterHash PKCS1v15Flaw = iota
+	//FlawShortPadding is a verifier that checks the DigestInfo is at the end
+	//of the block but only checks the first padding byte is 0xff. Garbage
+	//can be hidden in the rest of the padding. See verifyPKCS1v15ShortPadding.
+	FlawShortPadding
+	//FlawUncheckedParameters is a verifier that parses the DigestInfo but
+	//skips the AlgorithmIdentifier parameters without checking them, so
+	//garbage can be hidden in the parameters. This is one of the variants
+	//described by Kühn et al. See verifyPKCS1v15UncheckedParameters.
+	//
+	//BERserk hid garbage in overflowing length bytes instead, but a length
+	//holds at most 123 bytes of garbage while an e=3 forgery needs about 2k/3
+	//contiguous bytes of garbage, so that only works for a narrow range of
+	//moduli below 2048 bits.
+	FlawUncheckedParameters
+)
+
+//minPaddingLen is the minimum number of 0xff padding bytes required by
+//RFC 8017. Forgeries use this much padding when there is room for it and fall
+//back to the single byte the flawed verifiers accept otherwise.
+const minPaddingLen = 8
+
+//ForgePKCS1v15Signature forges a PKCS1v15 signature over message that is
+//accepted by a verifier with the supplied flaw. The public exponent must be 3.
+//
+//Only about a third of the bits of the block can be chosen, so the fixed
+//bytes of the forgery must fit in roughly k/3 bytes for a k byte modulus.
+//With SHA-1 every flaw can be forged from 1024 bit moduli up; larger hashes
+//need larger moduli. FlawShortPadding and FlawUncheckedParameters also need
+//the digest to be a cube modulo a power of two, so forging fails for some
+//messages. The returned error says why.
+func ForgePKCS1v15Signature(message []byte, hash crypto.Hash, publicKey *PublicKey, flaw PKCS1v15Flaw) (signature []byte, err error) {
+
+	if publicKey.E != 3 {
+		return nil, fmt.Errorf("forgery requires a public exponent of 3")
+	}
+	prefix, digest, err := hashMessage(message, hash)
+	if err != nil {
+		return
+	}
+	k := len(publicKey.N.Bytes())
+	digestInfo := append(append([]byte{}, prefix...), digest...)
+
+	var forgery *big.Int
+	switch flaw {
+	case FlawGarbageAfterHash:
+		//00 01 ff*n 00 DigestInfo || garbage
+		for _, n := range []int{minPaddingLen, 1} {
+			top := []byte{0x00, 0x01}
+			top = append(top, ffBytes(n)...)
+			top = append(top, 0x00)
+			top = append(top, digestInfo...)
+			if forgery, err = forgeTrailingGarbage(top, k); err == nil {
+				break
+			}
+		}
+	case FlawShortPadding:
+		//00 01 ff || garbage || 00 DigestInfo
+		top := []byte{0x00, 0x01, 0xff}
+		bottom := append([]byte{0x00}, digestInfo...)
+		forgery, err = forgeMiddleGarbage(top, bottom, k)
+	case FlawUncheckedParameters:
+		//00 01 ff*n 00 30 len 30 len OID 05 len || garbage || 04 len digest
+		oid, ok := digestInfoOID(prefix)
+		if !ok {
+			return nil, fmt.Errorf("unable to parse the DigestInfo prefix")
+		}
+		bottom := append([]byte{0x04, byte(len(digest))}, digest...)
+		for _, n := range []int{minPaddingLen, 1} {
+			top, ok := parametersTop(n, oid, len(bottom), k)
+			if !ok {
+				err = fmt.Errorf("modulus is too small for the DigestInfo")
+				continue
+			}
+			if forgery, err = forgeMiddleGarbage(top, bottom, k); err == nil {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown flaw")
+	}
+	if err != nil {
+		return
+	}
+
+	cube := new(big.Int).Exp(forgery, big.NewInt(3), nil)
+	if cube.Cmp(publicKey.N) >= 0 {
+		return nil, fmt.Errorf("forgery does not fit under the modulus")
+	}
+	signature = forgery.Bytes()
+	for len(signature) < k {
+		signature = append([]byte{0}, signature...)
+	}
+	return
+}
+
+//parametersTop returns the start of a k byte block for a
+//FlawUncheckedParameters forgery with n bytes of padding that ends with a
+//bottom byte digest OCTET STRING:
+//  00 01 ff*n 00 30 len 30 len OID 05 len
+//The parameters fill the rest of the block up to the digest. It returns false
+//if the block is too small.
+func parametersTop(n int, oid []byte, bottom, k int) (top []byte, ok bool) {
+	//The lengths and the sizes of their encodings depend on each other, so
+	//start with short form lengths and grow them until they are stable.
+	l1, l2, l3 := 1, 1, 1
+	for {
+		head := 2 + n + 1 + (1 + l1) + (1 + l2) + len(oid) + (1 + l3)
+		paramsLen := k - head - bottom
+		if paramsLen < 0 {
+			return nil, false
+		}
+		algLen := len(oid) + 1 + l3 + paramsLen
+		seqLen := 1 + l2 + algLen + bottom
+		if len(derLength(seqLen)) == l1 && len(derLength(algLen)) == l2 && len(derLength(paramsLen)) == l3 {
+			top = []byte{0x00, 0x01}
+			top = append(top, ffBytes(n)...)
+			top = append(top, 0x00, 0x30)
+			top = append(top, derLength(seqLen)...)
+			top = append(top, 0x30)
+			top = append(top, derLength(algLen)...)
+			top = append(top, oid...)
+			top = append(top, 0x05)
+			top = append(top, derLength(paramsLen)...)
+			return top, true
+		}
+		l1, l2, l3 = len(derLength(seqLen)), len(derLength(algLen)), len(derLength(paramsLen))
+	}
+}
+
+//derLength returns the DER encoding of length.
+func derLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var b []byte
+	for ; length > 0; length >>= 8 {
+		b = append([]byte{byte(length)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+//ffBytes returns n bytes of 0xff.
+func ffBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 0xff
+	}
+	return b
+}
+
+//forgeTrailingGarbage returns an integer whose cube is a k byte block that
+//starts with top. The low bytes of the cube are garbage.
+func forgeTrailingGarbage(top []byte, k int) (*big.Int, error) {
+	shift := uint(8 * (k - len(top)))
+	low := new(big.Int).Lsh(new(big.Int).SetBytes(top), shift)
+	high := new(big.Int).Add(low, new(big.Int).Lsh(big.NewInt(1), shift))
+
+	//The cube root ceiling is the smallest integer whose cube keeps top intact.
+	x := BigIntCubeRootFloor(low)
+	if new(big.Int).Exp(x, big.NewInt(3), nil).Cmp(low) < 0 {
+		x = x.Add(x, big.NewInt(1))
+	}
+	if new(big.Int).Exp(x, big.NewInt(3), nil).Cmp(high) >= 0 {
+		return nil, fmt.Errorf("not enough room for garbage after the hash")
+	}
+	return x, nil
+}
+
+//forgeMiddleGarbage returns an integer whose cube is a k byte block that
+//starts with top and ends with bottom. The bytes in between are garbage.
+//
+//The low bits are fixed by taking a cube root of bottom modulo 2^(8*len(bottom)).
+//The high bits are then chosen so the cube lands in the range that starts with
+//top. Changing the high bits of x never disturbs the low bits of x^3.
+func forgeMiddleGarbage(top, bottom []byte, k int) (*big.Int, error) {
+	m := uint(8 * len(bottom))
+	r, err := cubeRootMod2k(new(big.Int).SetBytes(bottom), m)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := uint(8 * (k - len(top)))
+	low := new(big.Int).Lsh(new(big.Int).SetBytes(top), shift)
+	high := new(big.Int).Add(low, new(big.Int).Lsh(big.NewInt(1), shift))
+
+	x := BigIntCubeRootFloor(low)
+	if new(big.Int).Exp(x, big.NewInt(3), nil).Cmp(low) < 0 {
+		x = x.Add(x, big.NewInt(1))
+	}
+	//Move x up to the next integer that is congruent to r mod 2^m.
+	mod := new(big.Int).Lsh(big.NewInt(1), m)
+	delta := new(big.Int).Sub(r, x)
+	delta = delta.Mod(delta, mod)
+	x = x.Add(x, delta)
+
+	if new(big.Int).Exp(x, big.NewInt(3), nil).Cmp(high) >= 0 {
+		return nil, fmt.Errorf("not enough room for garbage in the middle of the block")
+	}
+	return x, nil
+}
+
+//cubeRootMod2k returns r such that r^3 = a (mod 2^k). A solution only exists
+//when the number of trailing zero bits of a is a multiple of three.
+func cubeRootMod2k(a *big.Int, k uint) (*big.Int, error) {
+	mod := new(big.Int).Lsh(big.NewInt(1), k)
+	a = new(big.Int).Mod(a, mod)
+	if a.Sign() == 0 {
+		return big.NewInt(0), nil
+	}
+	zeros := a.TrailingZeroBits()
+	if zeros%3 != 0 {
+		return nil, fmt.Errorf("value is not a cube modulo 2^%d; try a different message", k)
+	}
+	odd := new(big.Int).Rsh(a, zeros)
+	bits := k - zeros
+
+	//Hensel lifting one bit at a time. Cubing is a bijection on odd residues
+	//mod 2^n so flipping bit i of r flips bit i of r^3.
+	r := big.NewInt(1)
+	cube := new(big.Int)
+	for i := uint(1); i < bits; i++ {
+		cube.Exp(r, big.NewInt(3), nil)
+		if cube.Bit(int(i)) != odd.Bit(int(i)) {
+			r.SetBit(r, int(i), 1)
+		}
+	}
+	return r.Lsh(r, zeros/3), nil
+}
+
+//verifyPKCS1v15ShortPadding verifies a PKCS1v15 signature but only checks the
+//first padding byte. It is vulnerable to forgeries that hide garbage in the
+//padding.
+func verifyPKCS1v15ShortPadding(message []byte, signature []byte, hash crypto.Hash, publicKey *PublicKey) error {
+
+	validationError := fmt.Errorf("invalid signature")
+	prefix, digest, err := hashMessage(message, hash)
+	if err != nil {
+		return err
+	}
+	blob := EncryptNoPadding(signature, publicKey)
+	suffix := append(append([]byte{0x00}, prefix...), digest...)
+	if len(blob) < len(suffix)+3 {
+		return validationError
+	}
+	if blob[0] != 0x00 || blob[1] != 0x01 || blob[2] != 0xff {
+		return validationError
+	}
+	if string(blob[len(blob)-len(suffix):]) != string(suffix) {
+		return validationError
+	}
+	return nil
+}
+
+//verifyPKCS1v15UncheckedParameters verifies a PKCS1v15 signature by parsing
+//the DigestInfo. It checks that the block is padded, that the DigestInfo ends
+//the block and that the OID and digest match, but it skips the
+//AlgorithmIdentifier parameters and, like verifyPKCS1v15Insecure, accepts any
+//amount of padding. It is vulnerable to forgeries that hide garbage in the
+//parameters.
+func verifyPKCS1v15UncheckedParameters(message []byte, signature []byte, hash crypto.Hash, publicKey *PublicKey) error {
+
+	validationError := fmt.Errorf("invalid signature")
+	prefix, digest, err := hashMessage(message, hash)
+	if err != nil {
+		return err
+	}
+	oid, ok := digestInfoOID(prefix)
+	if !ok {
+		return fmt.Errorf("unable to parse the DigestInfo prefix")
+	}
+	blob := EncryptNoPadding(signature, publicKey)
+	if len(blob) < 3 || blob[0] != 0x00 || blob[1] != 0x01 {
+		return validationError
+	}
+
+	index := 2
+	for index < len(blob) && blob[index] == 0xff {
+		index += 1
+	}
+	if index == 2 || index >= len(blob) || blob[index] != 0x00 {
+		return validationError
+	}
+	index += 1
+
+	//DigestInfo SEQUENCE
+	index, length, ok := readHeader(blob, index, 0x30)
+	if !ok || length != len(blob)-index {
+		return validationError
+	}
+
+	//AlgorithmIdentifier SEQUENCE. Everything after the OID is parameters
+	//and is skipped.
+	index, length, ok = readHeader(blob, index, 0x30)
+	if !ok || length > len(blob)-index {
+		return validationError
+	}
+	algEnd := index + length
+	if algEnd-index < len(oid) || string(blob[index:index+len(oid)]) != string(oid) {
+		return validationError
+	}
+	index = algEnd
+
+	//digest OCTET STRING
+	index, length, ok = readHeader(blob, index, 0x04)
+	if !ok || length != len(digest) || length != len(blob)-index {
+		return validationError
+	}
+	if string(blob[index:]) != string(digest) {
+		return validationError
+	}
+	return nil
+}
+
+//readHeader reads the tag and length of a BER element at index. Long form
+//lengths may use up to four bytes. It returns the index of the contents.
+func readHeader(blob []byte, index int, tag byte) (contents, length int, ok bool) {
+	if index+2 > len(blob) || blob[index] != tag {
+		return 0, 0, false
+	}
+	index += 1
+
+	var l uint32
+	if blob[index] < 0x80 {
+		l = uint32(blob[index])
+		index += 1
+	} else {
+		n := int(blob[index] & 0x7f)
+		index += 1
+		if n == 0 || n > 4 || index+n > len(blob) {
+			return 0, 0, false
+		}
+		for _, b := range blob[index : index+n] {
+			l = l<<8 | uint32(b)
+		}
+		index += n
+	}
+	return index, int(l), true
+}
+
+//digestInfoOID returns the encoded OID, including its tag and length, from a
+//DigestInfo prefix.
+func digestInfoOID(prefix []byte) (oid []byte, ok bool) {
+	index, _, ok := readHeader(prefix, 0, 0x30)
+	if !ok {
+		return nil, false
+	}
+	if index, _, ok = readHeader(prefix, index, 0x30); !ok {
+		return nil, false
+	}
+	contents, length, ok := readHeader(prefix, index, 0x06)
+	if !ok || contents+length > len(prefix) {
+		return nil, false
+	}
+	return prefix[index : contents+length], true
+}
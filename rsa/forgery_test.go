@@ -0,0 +1,136 @@
+package rsa
+
+import (
+	"crypto"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+//TestForgeryMatrix forges a signature for each verifier flaw and checks that
+//it is accepted only by the verifier with that flaw.
+func TestForgeryMatrix(t *testing.T) {
+
+	tests := []struct {
+		bits int
+		hash crypto.Hash
+	}{
+		//GenerateKey takes the size of the primes, so these are 1024 bit
+		//moduli, the Cryptopals 42 case, then 1536 and 2048 bit moduli
+		{512, crypto.SHA1},
+		{768, crypto.SHA1},
+		{1024, crypto.SHA1},
+		{1024, crypto.SHA256},
+	}
+	for _, te := range tests {
+		priv, err := GenerateKey(te.bits)
+		if err != nil {
+			t.Errorf("failed to generate key")
+			return
+		}
+		if !forgeryMatrix(t, priv, te.hash) {
+			t.Errorf("forgery matrix failed for a %d bit modulus", priv.PublicKey.N.BitLen())
+			return
+		}
+	}
+}
+
+//forgeryMatrix runs every forgery against every verifier for priv and hash.
+func forgeryMatrix(t *testing.T, priv *PrivateKey, hash crypto.Hash) bool {
+
+	verifiers := map[PKCS1v15Flaw]func([]byte, []byte, crypto.Hash, *PublicKey) error{
+		FlawGarbageAfterHash:    verifyPKCS1v15Insecure,
+		FlawShortPadding:        verifyPKCS1v15ShortPadding,
+		FlawUncheckedParameters: verifyPKCS1v15UncheckedParameters,
+	}
+	names := map[PKCS1v15Flaw]string{
+		FlawGarbageAfterHash:    "garbage after hash",
+		FlawShortPadding:        "short padding",
+		FlawUncheckedParameters: "unchecked parameters",
+	}
+
+	for flaw := range verifiers {
+		//Middle garbage forgeries only exist when the digest is a cube mod
+		//2^n, so try a few messages.
+		var message, forgery []byte
+		var err error
+		for i := 0; i < 64; i++ {
+			message = []byte(fmt.Sprintf("hi mom %d", i))
+			forgery, err = ForgePKCS1v15Signature(message, hash, priv.PublicKey, flaw)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			t.Errorf("failed to forge signature for %s: %v", names[flaw], err)
+			return false
+		}
+
+		for verifierFlaw, verify := range verifiers {
+			err = verify(message, forgery, hash, priv.PublicKey)
+			if verifierFlaw == flaw && err != nil {
+				t.Errorf("%s verifier rejected its forgery", names[flaw])
+				return false
+			}
+			if verifierFlaw != flaw && err == nil {
+				t.Errorf("%s verifier accepted the %s forgery", names[verifierFlaw], names[flaw])
+				return false
+			}
+		}
+
+		if VerifyPKCS1v15(message, forgery, hash, priv.PublicKey) == nil {
+			t.Errorf("strict verifier accepted the %s forgery", names[flaw])
+			return false
+		}
+	}
+	return true
+}
+
+func TestInsecureVerifiersAcceptValidSignatures(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+
+	message := []byte("thingy")
+	sig, err := SignPKCS1v15(message, crypto.SHA256, priv)
+	if err != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	verifiers := []func([]byte, []byte, crypto.Hash, *PublicKey) error{
+		verifyPKCS1v15Insecure,
+		verifyPKCS1v15ShortPadding,
+		verifyPKCS1v15UncheckedParameters,
+	}
+	for i, verify := range verifiers {
+		if err = verify(message, sig, crypto.SHA256, priv.PublicKey); err != nil {
+			t.Errorf("verifier %d rejected a valid signature", i)
+			return
+		}
+	}
+}
+
+func TestCubeRootMod2k(t *testing.T) {
+
+	for _, a := range []int64{1, 3, 27, 8 * 5, 1 << 9, 0x1234567} {
+		r, err := cubeRootMod2k(big.NewInt(a), 64)
+		if err != nil {
+			t.Errorf("no cube root found for %d", a)
+			return
+		}
+		cube := new(big.Int).Exp(r, big.NewInt(3), nil)
+		cube = cube.Mod(cube, new(big.Int).Lsh(big.NewInt(1), 64))
+		if cube.Cmp(big.NewInt(a)) != 0 {
+			t.Errorf("cube root of %d was incorrect", a)
+			return
+		}
+	}
+
+	if _, err := cubeRootMod2k(big.NewInt(2), 64); err == nil {
+		t.Errorf("found a cube root of 2 mod 2^64")
+		return
+	}
+}
@@ -0,0 +1,235 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"math/big"
+)
+
+const (
+	//PSSSaltLengthAuto makes VerifyPSS infer the salt length from the
+	//encoding. Accepting any salt length is a misuse: a verifier should know
+	//what the signer was configured to use. It is invalid when signing.
+	PSSSaltLengthAuto = -1
+	//PSSSaltLengthEqualsHash uses a salt as long as the hash output.
+	PSSSaltLengthEqualsHash = -2
+)
+
+//mgf1XOR XORs out with the MGF1 mask generated from seed using h, as
+//specified in RFC 8017 Appendix B.2.1.
+func mgf1XOR(out []byte, h hash.Hash, seed []byte) {
+	var counter [4]byte
+	var digest []byte
+	done := 0
+	for done < len(out) {
+		h.Reset()
+		h.Write(seed)
+		h.Write(counter[:])
+		digest = h.Sum(digest[:0])
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+		//increment the big-endian counter
+		for i := 3; i >= 0; i-- {
+			counter[i]++
+			if counter[i] != 0 {
+				break
+			}
+		}
+	}
+}
+
+//pssHash computes H = Hash(0x00*8 || mHash || salt).
+func pssHash(h hash.Hash, mHash, salt []byte) []byte {
+	h.Reset()
+	h.Write(make([]byte, 8))
+	h.Write(mHash)
+	h.Write(salt)
+	return h.Sum(nil)
+}
+
+//resolveSaltLength turns the PSSSaltLength constants into a byte count.
+func resolveSaltLength(saltLen int, hash crypto.Hash) int {
+	if saltLen == PSSSaltLengthEqualsHash {
+		return hash.Size()
+	}
+	return saltLen
+}
+
+//encodePSS returns the EMSA-PSS encoding of message as specified in RFC 8017
+//Section 9.1.1:
+//  maskedDB || H || 0xbc
+func encodePSS(message, salt []byte, hash crypto.Hash, emBits int) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("unsupported hash function")
+	}
+	h := hash.New()
+	h.Write(message)
+	mHash := h.Sum(nil)
+	hLen := len(mHash)
+	sLen := len(salt)
+
+	emLen := (emBits + 7) / 8
+	if emLen < hLen+sLen+2 {
+		return nil, fmt.Errorf("modulus is too short for the hash and salt lengths")
+	}
+
+	H := pssHash(h, mHash, salt)
+
+	em := make([]byte, emLen)
+	db := em[:emLen-hLen-1]
+	//DB = PS || 0x01 || salt where PS is all zeros
+	db[len(db)-sLen-1] = 0x01
+	copy(db[len(db)-sLen:], salt)
+	mgf1XOR(db, h, H)
+	//clear the bits above emBits
+	db[0] &= 0xff >> uint(8*emLen-emBits)
+	copy(em[emLen-hLen-1:], H)
+	em[emLen-1] = 0xbc
+	return em, nil
+}
+
+//SignPSS signs message with RSASSA-PSS using the supplied hash function for
+//both the message digest and MGF1. saltLen is the salt length in bytes or
+//PSSSaltLengthEqualsHash. A salt length of zero makes signatures
+//deterministic.
+func SignPSS(message []byte, hash crypto.Hash, saltLen int, privateKey *PrivateKey) (signature []byte, err error) {
+
+	saltLen = resolveSaltLength(saltLen, hash)
+	if saltLen < 0 {
+		return nil, fmt.Errorf("invalid salt length for signing")
+	}
+	salt := make([]byte, saltLen)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+
+	N := privateKey.PublicKey.N
+	em, err := encodePSS(message, salt, hash, N.BitLen()-1)
+	if err != nil {
+		return
+	}
+	signature = DecryptNoPadding(em, privateKey)
+	return
+}
+
+//openPSS recovers the encoded message from signature. It returns an error if
+//the signature is not a valid RSA value for publicKey.
+func openPSS(signature []byte, publicKey *PublicKey) (em []byte, emBits int, err error) {
+	k := len(publicKey.N.Bytes())
+	if len(signature) != k || new(big.Int).SetBytes(signature).Cmp(publicKey.N) >= 0 {
+		return nil, 0, fmt.Errorf("invalid signature")
+	}
+	emBits = publicKey.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+	m := EncryptNoPadding(signature, publicKey)
+	for len(m) < k {
+		m = append([]byte{0}, m...)
+	}
+	//when emBits is a multiple of 8 the encoded message is one byte
+	//shorter than the modulus and the leading byte must be zero.
+	for _, b := range m[:k-emLen] {
+		if b != 0 {
+			return nil, 0, fmt.Errorf("invalid signature")
+		}
+	}
+	return m[k-emLen:], emBits, nil
+}
+
+//VerifyPSS verifies an RSASSA-PSS signature over message. saltLen is the
+//expected salt length in bytes, PSSSaltLengthEqualsHash or
+//PSSSaltLengthAuto.
+func VerifyPSS(message, signature []byte, hash crypto.Hash, saltLen int, publicKey *PublicKey) error {
+
+	validationError := fmt.Errorf("invalid signature")
+	if !hash.Available() {
+		return fmt.Errorf("unsupported hash function")
+	}
+	em, emBits, err := openPSS(signature, publicKey)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(message)
+	mHash := h.Sum(nil)
+	hLen := len(mHash)
+	emLen := len(em)
+	saltLen = resolveSaltLength(saltLen, hash)
+
+	if emLen < hLen+2 || (saltLen >= 0 && emLen < hLen+saltLen+2) {
+		return validationError
+	}
+	if em[emLen-1] != 0xbc {
+		return validationError
+	}
+	topMask := byte(0xff << uint(8-(8*emLen-emBits)))
+	if 8*emLen-emBits == 0 {
+		topMask = 0
+	}
+	if em[0]&topMask != 0 {
+		return validationError
+	}
+
+	db := append([]byte{}, em[:emLen-hLen-1]...)
+	H := em[emLen-hLen-1 : emLen-1]
+	mgf1XOR(db, h, H)
+	db[0] &^= topMask
+
+	//DB must be PS || 0x01 || salt
+	sep := 0
+	for sep < len(db) && db[sep] == 0x00 {
+		sep++
+	}
+	if sep == len(db) || db[sep] != 0x01 {
+		return validationError
+	}
+	if saltLen >= 0 && sep != len(db)-saltLen-1 {
+		return validationError
+	}
+	salt := db[sep+1:]
+
+	if subtle.ConstantTimeCompare(pssHash(h, mHash, salt), H) != 1 {
+		return validationError
+	}
+	return nil
+}
+
+//verifyPSSInsecure verifies an RSASSA-PSS signature but skips the 0xbc
+//trailer check and never looks at the padding in DB. It takes the last
+//saltLen bytes of DB as the salt and only compares the hash. Unlike
+//verifyPKCS1v15Insecure this does not lead to a forgery on its own, but it
+//accepts encodings that a strict verifier rejects.
+func verifyPSSInsecure(message, signature []byte, hash crypto.Hash, saltLen int, publicKey *PublicKey) error {
+
+	validationError := fmt.Errorf("invalid signature")
+	if !hash.Available() {
+		return fmt.Errorf("unsupported hash function")
+	}
+	em, _, err := openPSS(signature, publicKey)
+	if err != nil {
+		return err
+	}
+	h := hash.New()
+	h.Write(message)
+	mHash := h.Sum(nil)
+	hLen := len(mHash)
+	emLen := len(em)
+	saltLen = resolveSaltLength(saltLen, hash)
+	if saltLen < 0 || emLen < hLen+saltLen+1 {
+		return validationError
+	}
+
+	db := append([]byte{}, em[:emLen-hLen-1]...)
+	H := em[emLen-hLen-1 : emLen-1]
+	mgf1XOR(db, h, H)
+	salt := db[len(db)-saltLen:]
+
+	if string(pssHash(h, mHash, salt)) != string(H) {
+		return validationError
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package rsa
+
+import (
+	"crypto"
+	"crypto/rand"
+	stdrsa "crypto/rsa"
+	"crypto/x509"
+	"testing"
+)
+
+func TestPSSMatchesStdlib(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	der, err := MarshalPKCS1PrivateKey(priv)
+	if err != nil {
+		t.Errorf("failed to marshal key")
+		return
+	}
+	stdPriv, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		t.Errorf("stdlib failed to parse key: %v", err)
+		return
+	}
+
+	message := []byte("thingy")
+	hashes := []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384}
+	for _, hash := range hashes {
+		h := hash.New()
+		h.Write(message)
+		digest := h.Sum(nil)
+
+		sig, err := SignPSS(message, hash, PSSSaltLengthEqualsHash, priv)
+		if err != nil {
+			t.Errorf("failed to sign message: %v", err)
+			return
+		}
+		opts := &stdrsa.PSSOptions{SaltLength: stdrsa.PSSSaltLengthEqualsHash}
+		if err = stdrsa.VerifyPSS(&stdPriv.PublicKey, hash, digest, sig, opts); err != nil {
+			t.Errorf("stdlib rejected signature for %v: %v", hash, err)
+			return
+		}
+
+		stdSig, err := stdrsa.SignPSS(rand.Reader, stdPriv, hash, digest, opts)
+		if err != nil {
+			t.Errorf("stdlib failed to sign message: %v", err)
+			return
+		}
+		if err = VerifyPSS(message, stdSig, hash, PSSSaltLengthEqualsHash, priv.PublicKey); err != nil {
+			t.Errorf("rejected stdlib signature for %v", hash)
+			return
+		}
+		if err = VerifyPSS([]byte("thingies"), stdSig, hash, PSSSaltLengthEqualsHash, priv.PublicKey); err == nil {
+			t.Errorf("accepted signature for the wrong message")
+			return
+		}
+	}
+}
+
+func TestPSSSaltLengthMisuse(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("thingy")
+
+	//A zero length salt makes PSS deterministic.
+	sig1, err1 := SignPSS(message, crypto.SHA256, 0, priv)
+	sig2, err2 := SignPSS(message, crypto.SHA256, 0, priv)
+	if err1 != nil || err2 != nil {
+		t.Errorf("failed to sign message")
+		return
+	}
+	if string(sig1) != string(sig2) {
+		t.Errorf("zero length salt signatures were not deterministic")
+		return
+	}
+
+	//A verifier expecting a hash length salt rejects the signature but a
+	//verifier that infers the salt length accepts it.
+	if VerifyPSS(message, sig1, crypto.SHA256, PSSSaltLengthEqualsHash, priv.PublicKey) == nil {
+		t.Errorf("accepted a signature with the wrong salt length")
+		return
+	}
+	if err = VerifyPSS(message, sig1, crypto.SHA256, PSSSaltLengthAuto, priv.PublicKey); err != nil {
+		t.Errorf("auto salt length rejected a valid signature")
+		return
+	}
+
+	if _, err = SignPSS(message, crypto.SHA256, PSSSaltLengthAuto, priv); err == nil {
+		t.Errorf("signed with an automatic salt length")
+		return
+	}
+}
+
+//TestPSSInsecureVerifier builds encodings with a bad trailer and bad padding
+//and signs them directly. Only the insecure verifier accepts them.
+func TestPSSInsecureVerifier(t *testing.T) {
+
+	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
+	message := []byte("thingy")
+	hash := crypto.SHA256
+	saltLen := hash.Size()
+	salt := make([]byte, saltLen)
+
+	em, err := encodePSS(message, salt, hash, priv.PublicKey.N.BitLen()-1)
+	if err != nil {
+		t.Errorf("failed to encode message")
+		return
+	}
+
+	badTrailer := append([]byte{}, em...)
+	badTrailer[len(badTrailer)-1] = 0xcc
+
+	//flipping a bit in the masked DB corrupts PS without touching the salt
+	badPadding := append([]byte{}, em...)
+	badPadding[1] ^= 0x01
+
+	for _, bad := range [][]byte{badTrailer, badPadding} {
+		sig := DecryptNoPadding(bad, priv)
+		if err = verifyPSSInsecure(message, sig, hash, saltLen, priv.PublicKey); err != nil {
+			t.Errorf("insecure verifier rejected a malformed encoding")
+			return
+		}
+		if VerifyPSS(message, sig, hash, saltLen, priv.PublicKey) == nil {
+			t.Errorf("strict verifier accepted a malformed encoding")
+			return
+		}
+	}
+}
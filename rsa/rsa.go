@@ -1,8 +1,13 @@
 package rsa
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/sha1"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/asn1"
 	"fmt"
 	badbig "github.com/kelbyludwig/badcrypto/big"
 	"math/big"
@@ -32,39 +37,122 @@ func encryptNoPaddingMontgomery(plaintext []byte, publicKey *PublicKey) (ciphert
 	return
 }
 
-//SignPKCS1v15 signs plaintext with PKCS1v15 padding using SHA256
-//as the underlying hash function. For simplicitys sake, the SHA256 hash
-//is not encoding with the PKCS1v15 ASN encoding.
-func SignPKCS1v15(plaintext []byte, privateKey *PrivateKey) (signature []byte) {
+//digestInfo mirrors the DigestInfo structure from RFC 8017 Section 9.2.
+type digestInfo struct {
+	Algorithm algorithmIdentifier
+	Digest    []byte
+}
+
+//hashOIDs maps the supported hash functions to their ASN.1 object identifiers.
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   {1, 3, 14, 3, 2, 26},
+	crypto.SHA224: {2, 16, 840, 1, 101, 3, 4, 2, 4},
+	crypto.SHA256: {2, 16, 840, 1, 101, 3, 4, 2, 1},
+	crypto.SHA384: {2, 16, 840, 1, 101, 3, 4, 2, 2},
+	crypto.SHA512: {2, 16, 840, 1, 101, 3, 4, 2, 3},
+}
+
+//hashPrefixes holds the DER encoded DigestInfo prefix for each supported hash
+//function. It is populated by init() from hashOIDs.
+var hashPrefixes = make(map[crypto.Hash][]byte)
+
+func init() {
+	for hash, oid := range hashOIDs {
+		//Encode a DigestInfo with an all-zero digest and strip the digest
+		//off the end. What is left is the prefix that precedes every digest.
+		der, err := asn1.Marshal(digestInfo{
+			Algorithm: algorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+			Digest:    make([]byte, hash.Size()),
+		})
+		if err != nil {
+			panic("failed to generate DigestInfo prefixes")
+		}
+		hashPrefixes[hash] = der[:len(der)-hash.Size()]
+	}
+}
+
+//hashMessage returns the prefix for hash and the digest of message under hash.
+func hashMessage(message []byte, hash crypto.Hash) (prefix, digest []byte, err error) {
+	prefix, ok := hashPrefixes[hash]
+	if !ok || !hash.Available() {
+		return nil, nil, fmt.Errorf("unsupported hash function")
+	}
+	h := hash.New()
+	h.Write(message)
+	return prefix, h.Sum(nil), nil
+}
+
+//encodePKCS1v15 returns the EMSA-PKCS1-v1_5 encoding of message for a modulus
+//that is k bytes long:
+//  0x00 || 0x01 || 0xff... || 0x00 || DigestInfo
+func encodePKCS1v15(message []byte, hash crypto.Hash, k int) (encoded []byte, err error) {
+	prefix, digest, err := hashMessage(message, hash)
+	if err != nil {
+		return
+	}
+	tLen := len(prefix) + len(digest)
+	if k < tLen+11 {
+		return nil, fmt.Errorf("modulus is too short for the hash function")
+	}
+	encoded = make([]byte, k)
+	encoded[0] = 0x00
+	encoded[1] = 0x01
+	for i := 2; i < k-tLen-1; i++ {
+		encoded[i] = 0xff
+	}
+	encoded[k-tLen-1] = 0x00
+	copy(encoded[k-tLen:], prefix)
+	copy(encoded[k-len(digest):], digest)
+	return
+}
 
-	digest := sha1.Sum(plaintext)
-	digestLen := len(digest)
+//SignPKCS1v15 signs plaintext with PKCS1v15 padding using the supplied hash
+//function. The digest is wrapped in a DER encoded DigestInfo structure as
+//specified in RFC 8017.
+func SignPKCS1v15(plaintext []byte, hash crypto.Hash, privateKey *PrivateKey) (signature []byte, err error) {
 	//TIL: the stdlib's method of pkcs1v15 signing hardcodes asn der bytes for hash functions used in tls
 	//https://golang.org/src/crypto/rsa/pkcs1v15.go#L205
-	sha1Prefix := []byte{0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14}
-	prefixLen := len(sha1Prefix)
-	pubLen := len(privateKey.PublicKey.N.Bytes())
-	signature = make([]byte, pubLen)
-	signature[0] = 0x00
-	signature[1] = 0x01
-
-	endpad := pubLen - prefixLen - digestLen
-	for i := 2; i < endpad-1; i++ {
-		signature[i] = 0xff
-	}
-	signature[endpad-1] = 0x00
-	copy(signature[endpad:], sha1Prefix)
-	copy(signature[endpad+prefixLen:], digest[:])
-	signature = DecryptNoPadding(signature, privateKey)
+	//The prefixes used here are generated from the hash OIDs instead.
+	encoded, err := encodePKCS1v15(plaintext, hash, len(privateKey.PublicKey.N.Bytes()))
+	if err != nil {
+		return
+	}
+	signature = DecryptNoPadding(encoded, privateKey)
 	return
+}
 
+//VerifyPKCS1v15 verifies a PKCS1v15 signature over message. The expected
+//encoding is rebuilt from message and compared in constant time against the
+//decrypted signature, so no padding is ever parsed.
+func VerifyPKCS1v15(message, signature []byte, hash crypto.Hash, publicKey *PublicKey) error {
+	validationError := fmt.Errorf("invalid signature")
+	k := len(publicKey.N.Bytes())
+	if len(signature) != k || new(big.Int).SetBytes(signature).Cmp(publicKey.N) >= 0 {
+		return validationError
+	}
+	expected, err := encodePKCS1v15(message, hash, k)
+	if err != nil {
+		return err
+	}
+	blob := EncryptNoPadding(signature, publicKey)
+	for len(blob) < k {
+		blob = append([]byte{0}, blob...)
+	}
+	if subtle.ConstantTimeCompare(blob, expected) != 1 {
+		return validationError
+	}
+	return nil
 }
 
 //verifyPKCS1v15Insecure will verify the validity of signatures generated by Sign.
 //It is vulnerable to the signature forgery attack described by Bleichenbacher.
-func verifyPKCS1v15Insecure(message []byte, signature []byte, publicKey *PublicKey) error {
+func verifyPKCS1v15Insecure(message []byte, signature []byte, hash crypto.Hash, publicKey *PublicKey) error {
 
 	validationError := fmt.Errorf("invalid signature")
+	prefix, digest, err := hashMessage(message, hash)
+	if err != nil {
+		return err
+	}
 	blob := EncryptNoPadding(signature, publicKey)
 	if blob[0] != 0x00 || blob[1] != 0x01 {
 		return validationError
@@ -73,6 +161,9 @@ func verifyPKCS1v15Insecure(message []byte, signature []byte, publicKey *PublicK
 	index := 2
 	for {
 		index += 1
+		if index >= len(blob) {
+			return validationError
+		}
 		x := blob[index]
 		if x == 0xff {
 			continue
@@ -83,17 +174,17 @@ func verifyPKCS1v15Insecure(message []byte, signature []byte, publicKey *PublicK
 		return validationError
 	}
 	index += 1
-	sha1Prefix := []byte{0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14}
-	prefixLen := len(sha1Prefix)
-	if string(blob[index:index+prefixLen]) != string(sha1Prefix) {
+	prefixLen := len(prefix)
+	hashLen := len(digest)
+	if len(blob) < index+prefixLen+hashLen {
+		return validationError
+	}
+	if string(blob[index:index+prefixLen]) != string(prefix) {
 		return validationError
 	}
 	index = index + prefixLen
 
-	//assuming sha1
-	hashLen := 20
-	digest := sha1.Sum(message)
-	if string(blob[index:index+hashLen]) != string(digest[:]) {
+	if string(blob[index:index+hashLen]) != string(digest) {
 		return validationError
 	}
 	return nil
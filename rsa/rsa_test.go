@@ -1,7 +1,9 @@
 package rsa
 
 import (
-	"crypto/sha1"
+	"crypto"
+	stdrsa "crypto/rsa"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"math/big"
@@ -165,6 +167,10 @@ func TestMontgomeryExtraReductionsCount(t *testing.T) {
 func TestUnpaddedMessageRecovery(t *testing.T) {
 
 	priv, err := GenerateKey(512)
+	if err != nil {
+		t.Errorf("failed to generate key")
+		return
+	}
 
 	dupes := make(map[string]bool)
 	decryptNoDupes := func(ct []byte) (pt []byte, err error) {
@@ -186,7 +192,7 @@ func TestUnpaddedMessageRecovery(t *testing.T) {
 		return
 	}
 
-	if string(sm1) != string(secretMessage) {
+	if new(big.Int).SetBytes(sm1).Cmp(new(big.Int).SetBytes(secretMessage)) != 0 {
 		t.Errorf("failed to properly decrypt ciphertext")
 		return
 	}
@@ -198,32 +204,19 @@ func TestUnpaddedMessageRecovery(t *testing.T) {
 		return
 	}
 
-	//create ((s**e mod n) c) mod n
-	//where s is a random integer between 1 and n. here, s is 42.
-	c := new(big.Int).SetBytes(secretCiphertext)
-	s := big.NewInt(42)
-	e := big.NewInt(priv.PublicKey.E)
-	n := priv.PublicKey.N
-	inverse := new(big.Int).ModInverse(s, n)
-	s = s.Exp(s, e, n)
-	s = s.Mul(s, c)
-	s = s.Mod(s, n)
-
-	sm3, err := decryptNoDupes(s.Bytes())
+	//create ((s**e mod n) c) mod n for a random s, have the server decrypt it
+	//and divide s back out.
+	plaintext, err := RecoverViaBlinding(secretCiphertext, priv.PublicKey, decryptNoDupes)
 
 	if err != nil {
 		t.Errorf("the server failed to decrypt our dupe ciphertext")
 		return
 	}
 
-	plaintext := new(big.Int).SetBytes(sm3)
-	plaintext = plaintext.Mul(plaintext, inverse)
-	plaintext = plaintext.Mod(plaintext, n)
-
-	if string(plaintext.Bytes()) != string(secretMessage) {
+	if string(plaintext) != string(secretMessage) {
 		t.Errorf("the decryption of our secret message was wrong")
 		t.Logf("expected: %s\n", secretMessage)
-		t.Logf("result:   %s\n", sm3)
+		t.Logf("result:   %s\n", plaintext)
 		return
 	}
 }
@@ -238,15 +231,76 @@ func TestRSASign(t *testing.T) {
 	}
 
 	message := []byte("thingy")
-	sig := SignPKCS1v15(message, priv)
+	hashes := []crypto.Hash{crypto.SHA1, crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512}
+	for _, hash := range hashes {
+		sig, err := SignPKCS1v15(message, hash, priv)
+		if err != nil {
+			t.Errorf("failed to sign message: %v", err)
+			return
+		}
+
+		err = verifyPKCS1v15Insecure(message, sig, hash, priv.PublicKey)
+		if err != nil {
+			t.Errorf("RSA signature validation failed unecessarily")
+			return
+		}
+
+		err = VerifyPKCS1v15(message, sig, hash, priv.PublicKey)
+		if err != nil {
+			t.Errorf("RSA signature validation failed unecessarily")
+			return
+		}
+
+		err = VerifyPKCS1v15([]byte("thingies"), sig, hash, priv.PublicKey)
+		if err == nil {
+			t.Errorf("RSA signature validation succeeded for the wrong message")
+			return
+		}
+	}
+
+}
 
-	err = verifyPKCS1v15Insecure(message, sig, priv.PublicKey)
+//TestSignPKCS1v15MatchesStdlib checks the generated DigestInfo prefixes by
+//comparing signatures against crypto/rsa. PKCS1v15 signatures are
+//deterministic so they should be byte-for-byte identical.
+func TestSignPKCS1v15MatchesStdlib(t *testing.T) {
 
+	priv, err := GenerateKey(512)
 	if err != nil {
-		t.Errorf("RSA signature validation failed unecessarily")
+		t.Errorf("failed to generate key")
+		return
+	}
+	der, err := MarshalPKCS1PrivateKey(priv)
+	if err != nil {
+		t.Errorf("failed to marshal key")
+		return
+	}
+	stdPriv, err := x509.ParsePKCS1PrivateKey(der)
+	if err != nil {
+		t.Errorf("stdlib failed to parse key: %v", err)
 		return
 	}
 
+	message := []byte("thingy")
+	hashes := []crypto.Hash{crypto.SHA1, crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512}
+	for _, hash := range hashes {
+		sig, err := SignPKCS1v15(message, hash, priv)
+		if err != nil {
+			t.Errorf("failed to sign message: %v", err)
+			return
+		}
+		h := hash.New()
+		h.Write(message)
+		stdSig, err := stdrsa.SignPKCS1v15(nil, stdPriv, hash, h.Sum(nil))
+		if err != nil {
+			t.Errorf("stdlib failed to sign message: %v", err)
+			return
+		}
+		if string(sig) != string(stdSig) {
+			t.Errorf("signature did not match stdlib signature for %v", hash)
+			return
+		}
+	}
 }
 
 //TestSmallExponentSignatureForgery is a test for Cryptopals Set 6 Challenge 42
@@ -259,27 +313,27 @@ func TestSmallExponentSignatureForgery(t *testing.T) {
 		return
 	}
 
-	modulusSize := 128
-	sha1Prefix := []byte{0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14}
-	shortPKCS := []byte{0x00, 0x01, 0xff, 0x00}
 	message := []byte("hi mom")
-	messageDigest := sha1.Sum(message)
-	numZeros := modulusSize - len(sha1Prefix) - len(shortPKCS) - len(messageDigest)
-	zeros := make([]byte, numZeros)
-	forgery := append(shortPKCS, sha1Prefix...)
-	forgery = append(forgery, messageDigest[:]...)
-	forgery = append(forgery, zeros...)
-	forgeryNum := new(big.Int).SetBytes(forgery)
-	forgeryNum = BigIntCubeRootFloor(forgeryNum)
-	//Grr... I needed the cube root ceiling here. Adding one!
-	forgeryNum = forgeryNum.Add(forgeryNum, big.NewInt(1))
-	forgery = forgeryNum.Bytes()
+	forgery, err := ForgePKCS1v15Signature(message, crypto.SHA1, priv.PublicKey, FlawGarbageAfterHash)
+
+	if err != nil {
+		t.Errorf("failed to forge signature: %v", err)
+		return
+	}
 
 	//check the forged signature
-	err = verifyPKCS1v15Insecure(message, forgery, priv.PublicKey)
+	err = verifyPKCS1v15Insecure(message, forgery, crypto.SHA1, priv.PublicKey)
 
 	if err != nil {
 		t.Errorf("RSA signature validation failed unecessarily")
 		return
 	}
+
+	//the strict verifier does not parse the padding so it is not fooled
+	err = VerifyPKCS1v15(message, forgery, crypto.SHA1, priv.PublicKey)
+
+	if err == nil {
+		t.Errorf("RSA signature validation accepted a forgery")
+		return
+	}
 }